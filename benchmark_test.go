@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/solo-io/protoc-gen-openapi/pkg/protomodel"
+)
+
+// newDiamondRequest builds a request for a synthetic proto file in which every message of a layer
+// references every message of the next layer, so the message graph is made of overlapping diamonds.
+func newDiamondRequest(layers int, width int) *pluginpb.CodeGeneratorRequest {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:           proto.String("bench/diamond.proto"),
+		Package:        proto.String("bench"),
+		Syntax:         proto.String("proto3"),
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+	}
+
+	for l := 0; l < layers; l++ {
+		for w := 0; w < width; w++ {
+			msgIdx := int32(len(file.MessageType))
+			msg := &descriptorpb.DescriptorProto{
+				Name: proto.String(fmt.Sprintf("Layer%dMsg%d", l, w)),
+			}
+			file.SourceCodeInfo.Location = append(file.SourceCodeInfo.Location, &descriptorpb.SourceCodeInfo_Location{
+				Path:            []int32{4, msgIdx},
+				LeadingComments: proto.String(" A message of the diamond.\n\n +kubebuilder:validation:MaxProperties=100\n"),
+			})
+
+			addField := func(field *descriptorpb.FieldDescriptorProto, comment string) {
+				field.Number = proto.Int32(int32(len(msg.Field) + 1))
+				field.JsonName = proto.String(field.GetName())
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+				file.SourceCodeInfo.Location = append(file.SourceCodeInfo.Location, &descriptorpb.SourceCodeInfo_Location{
+					Path:            []int32{4, msgIdx, 2, int32(len(msg.Field))},
+					LeadingComments: proto.String(comment),
+				})
+				msg.Field = append(msg.Field, field)
+			}
+
+			addField(&descriptorpb.FieldDescriptorProto{
+				Name: proto.String("name"),
+				Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}, " The name.\n\n +kubebuilder:validation:MaxLength=64\n")
+			addField(&descriptorpb.FieldDescriptorProto{
+				Name: proto.String("count"),
+				Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+			}, " The count.\n")
+
+			if l+1 < layers {
+				for next := 0; next < width; next++ {
+					addField(&descriptorpb.FieldDescriptorProto{
+						Name:     proto.String(fmt.Sprintf("next%d", next)),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(fmt.Sprintf(".bench.Layer%dMsg%d", l+1, next)),
+					}, " A message of the next layer.\n")
+				}
+			}
+
+			file.MessageType = append(file.MessageType, msg)
+		}
+	}
+
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	}
+}
+
+func BenchmarkGenerateDiamond(b *testing.B) {
+	for _, size := range []struct{ layers, width int }{{3, 4}, {4, 5}, {5, 5}} {
+		b.Run(fmt.Sprintf("layers=%d,width=%d", size.layers, size.width), func(b *testing.B) {
+			request := newDiamondRequest(size.layers, size.width)
+			m := protomodel.NewModel(request, false)
+			filesToGen := map[*protomodel.FileDescriptor]bool{m.AllFilesByName["bench/diamond.proto"]: true}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g := newOpenAPIGenerator(m, GeneratorOptions{
+					SingleFile:  true,
+					Description: &DescriptionConfiguration{IncludeDescriptionInSchema: true, MultilineDescription: true},
+				})
+				if _, err := g.generateOutput(filesToGen); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
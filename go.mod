@@ -6,7 +6,7 @@ require (
 	github.com/getkin/kin-openapi v0.131.0
 	github.com/ghodss/yaml v1.0.0
 	github.com/golang/protobuf v1.5.3
-	google.golang.org/protobuf v1.31.0
+	google.golang.org/protobuf v1.36.11
 	sigs.k8s.io/controller-tools v0.14.0
 )
 
@@ -34,6 +34,8 @@ func TestOpenAPIGeneration(t *testing.T) {
 		inputFiles map[string][]string
 		protocArgs []string
 		wantFiles  []string
+		// the error the generation fails with, instead of generating wantFiles
+		wantErr string
 	}{
 		{
 			name:       "Per Package Generation",
@@ -139,6 +141,426 @@ func TestOpenAPIGeneration(t *testing.T) {
 			},
 			wantFiles: []string{"test10/openapiv3.yaml"},
 		},
+		{
+			name:       "Test exclude_hidden option",
+			id:         "test11",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,proto_oneof=true,multiline_description=true,exclude_hidden=true",
+			inputFiles: map[string][]string{
+				"test11": {"./testdata/test11/hidden.proto"},
+			},
+			wantFiles: []string{"test11/openapiv3.yaml"},
+		},
+		{
+			name:       "Test classes option excludes a class and adds stability extension",
+			id:         "test12",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,proto_oneof=true,classes=-experimental,stability_extension=true",
+			inputFiles: map[string][]string{
+				"test12": {"./testdata/test12/classes.proto"},
+			},
+			wantFiles: []string{"test12/openapiv3.yaml"},
+		},
+		{
+			name:       "Test classes option only includes a class",
+			id:         "test13",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,proto_oneof=true,classes=stable",
+			inputFiles: map[string][]string{
+				"test12": {"./testdata/test12/classes.proto"},
+			},
+			wantFiles: []string{"test13/openapiv3.yaml"},
+		},
+		{
+			name:       "Test proto3 optional fields",
+			id:         "test14",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,proto_oneof=true",
+			inputFiles: map[string][]string{
+				"test14": {"./testdata/test14/optional.proto"},
+			},
+			wantFiles: []string{"test14/openapiv3.yaml"},
+		},
+		{
+			name:       "Test protobuf editions features",
+			id:         "test15",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,enum_as_int_or_string=true",
+			inputFiles: map[string][]string{
+				"test15": {"./testdata/test15/editions.proto", "./testdata/test15/inherited.proto"},
+			},
+			wantFiles: []string{"test15/openapiv3.yaml"},
+		},
+		{
+			name:       "Test proto2 required fields, default values and groups",
+			id:         "test16",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,int_native=true",
+			inputFiles: map[string][]string{
+				"test16": {"./testdata/test16/proto2.proto"},
+			},
+			wantFiles: []string{"test16/openapiv3.yaml"},
+		},
+		{
+			name:       "Test recursive messages are truncated",
+			id:         "test17",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,recursion_depth=1",
+			inputFiles: map[string][]string{
+				"test17": {"./testdata/test17/recursive.proto"},
+			},
+			wantFiles: []string{"test17/openapiv3.yaml"},
+		},
+		{
+			name:       "Test recursive messages use $ref",
+			id:         "test18",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,recursion=ref",
+			inputFiles: map[string][]string{
+				"test17": {"./testdata/test17/recursive.proto"},
+			},
+			wantFiles: []string{"test18/openapiv3.yaml"},
+		},
+		{
+			name:       "Test nested messages and enums use $ref",
+			id:         "test19",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,use_ref=true,ref_mode=ref_nested",
+			inputFiles: map[string][]string{
+				"test19": {"./testdata/test19/refs.proto"},
+			},
+			wantFiles: []string{"test19/openapiv3.yaml"},
+		},
+		{
+			name:       "Test all messages and enums use $ref",
+			id:         "test20",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,use_ref=true,ref_mode=ref_all",
+			inputFiles: map[string][]string{
+				"test19": {"./testdata/test19/refs.proto"},
+			},
+			wantFiles: []string{"test20/openapiv3.yaml"},
+		},
+		{
+			name:       "Test types of other packages use $ref to their output file",
+			id:         "test21",
+			perPackage: false,
+			genOpts:    "yaml=true,multiline_description=true,external_ref=file",
+			inputFiles: map[string][]string{
+				"test19": {"./testdata/test19/refs.proto", "./testdata/test19/shared.proto"},
+			},
+			wantFiles: []string{"test21/test19.yaml", "test21/test19.shared.yaml"},
+		},
+		{
+			name:       "Test types of other packages use $ref to their location",
+			id:         "test22",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,external_ref=location",
+			inputFiles: map[string][]string{
+				"test19": {"./testdata/test19/refs.proto"},
+			},
+			wantFiles: []string{"test22/openapiv3.yaml"},
+		},
+		{
+			name:       "Test fields use their proto names",
+			id:         "test23",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,proto_oneof=true,field_naming=proto",
+			inputFiles: map[string][]string{
+				"test23": {"./testdata/test23/naming.proto"},
+			},
+			wantFiles: []string{"test23/openapiv3.yaml"},
+		},
+		{
+			name:       "Test fields accept both their JSON and proto names",
+			id:         "test24",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,proto_oneof=true,field_naming=both",
+			inputFiles: map[string][]string{
+				"test23": {"./testdata/test23/naming.proto"},
+			},
+			wantFiles: []string{"test24/openapiv3.yaml"},
+		},
+		{
+			name:       "Test 64-bit integers and floats are modeled as encoded by protojson",
+			id:         "test25",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,protojson_numbers=true",
+			inputFiles: map[string][]string{
+				"test25": {"./testdata/test25/numbers.proto"},
+			},
+			wantFiles: []string{"test25/openapiv3.yaml"},
+		},
+		{
+			name:       "Test well-known and common types",
+			id:         "test26",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true",
+			inputFiles: map[string][]string{
+				"test26": {"./testdata/test26/wkt.proto"},
+			},
+			wantFiles: []string{"test26/openapiv3.yaml"},
+		},
+		{
+			name:       "Test schemas are replaced and patched by an overrides file",
+			id:         "test27",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,overrides=testdata/test27/overrides.yaml",
+			inputFiles: map[string][]string{
+				"test27": {"./testdata/test27/overrides.proto"},
+			},
+			wantFiles: []string{"test27/openapiv3.yaml"},
+		},
+		{
+			name:       "Test empty schemas are matched by patterns",
+			id:         "test28",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,additional_empty_schema=test28.config.**+**.Metadata",
+			inputFiles: map[string][]string{
+				"test28": {"./testdata/test28/patterns.proto"},
+			},
+			wantFiles: []string{"test28/openapiv3.yaml"},
+		},
+		{
+			name:       "Test enums list the names and descriptions of their values",
+			id:         "test29",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,enum_int_values=true",
+			inputFiles: map[string][]string{
+				"test29": {"./testdata/test29/enums.proto"},
+			},
+			wantFiles: []string{"test29/openapiv3.yaml"},
+		},
+		{
+			name:       "Test int or string enums validate both names and numbers",
+			id:         "test30",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,enum_as_int_or_string=true,enum_int_values=true",
+			inputFiles: map[string][]string{
+				"test29": {"./testdata/test29/enums.proto"},
+			},
+			wantFiles: []string{"test30/openapiv3.yaml"},
+		},
+		{
+			name:       "Test unspecified, alias and deprecated enum values are omitted",
+			id:         "test31",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,exclude_unspecified=true,enum_aliases=canonical,deprecated_enum_values=drop",
+			inputFiles: map[string][]string{
+				"test31": {"./testdata/test31/values.proto"},
+			},
+			wantFiles: []string{"test31/openapiv3.yaml"},
+		},
+		{
+			name:       "Test deprecated enum values are annotated",
+			id:         "test32",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,deprecated_enum_values=annotate",
+			inputFiles: map[string][]string{
+				"test31": {"./testdata/test31/values.proto"},
+			},
+			wantFiles: []string{"test32/openapiv3.yaml"},
+		},
+		{
+			name:       "Test deprecated fields, messages and enums are marked",
+			id:         "test33",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,deprecation=mark",
+			inputFiles: map[string][]string{
+				"test33": {"./testdata/test33/deprecated.proto"},
+			},
+			wantFiles: []string{"test33/openapiv3.yaml"},
+		},
+		{
+			name:       "Test deprecated fields, messages and enums have a note and a warning",
+			id:         "test34",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,deprecation=note,deprecation_warning=true",
+			inputFiles: map[string][]string{
+				"test33": {"./testdata/test33/deprecated.proto"},
+			},
+			wantFiles: []string{"test34/openapiv3.yaml"},
+		},
+		{
+			name:       "Test google.api.field_behavior is translated to the schema",
+			id:         "test35",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true",
+			inputFiles: map[string][]string{
+				"test35": {"./testdata/test35/behavior.proto"},
+			},
+			wantFiles: []string{"test35/openapiv3.yaml"},
+		},
+		{
+			name:       "Test google.api.resource names are validated against their patterns",
+			id:         "test36",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true",
+			inputFiles: map[string][]string{
+				"test36": {"./testdata/test36/resources.proto"},
+			},
+			wantFiles: []string{"test36/openapiv3.yaml"},
+		},
+		{
+			name:       "Test proto oneofs are rendered as variants",
+			id:         "test37",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,int_native=true,proto_oneof=variants,oneof_name_extension=true",
+			inputFiles: map[string][]string{
+				"test37": {"./testdata/test37/variants.proto"},
+			},
+			wantFiles: []string{"test37/openapiv3.yaml"},
+		},
+		{
+			name:       "Test required proto oneofs allow exactly one member",
+			id:         "test38",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,int_native=true,proto_oneof=true",
+			inputFiles: map[string][]string{
+				"test37": {"./testdata/test37/variants.proto"},
+			},
+			wantFiles: []string{"test38/openapiv3.yaml"},
+		},
+		{
+			name:       "Test required proto oneofs need a member without proto_oneof",
+			id:         "test39",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,int_native=true,proto_oneof=false",
+			inputFiles: map[string][]string{
+				"test37": {"./testdata/test37/variants.proto"},
+			},
+			wantFiles: []string{"test39/openapiv3.yaml"},
+		},
+		{
+			name:       "Test map keys are validated",
+			id:         "test40",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,validate_map_keys=true",
+			inputFiles: map[string][]string{
+				"test40": {"./testdata/test40/maps.proto"},
+			},
+			wantFiles: []string{"test40/openapiv3.yaml"},
+		},
+		{
+			name:       "Test strict message schemas don't accept unknown properties",
+			id:         "test41",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,strict=true",
+			inputFiles: map[string][]string{
+				"test41": {"./testdata/test41/strict.proto"},
+			},
+			wantFiles: []string{"test41/openapiv3.yaml"},
+		},
+		{
+			name:       "Test strict oneof variants don't accept unknown properties",
+			id:         "test42",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,strict=true,proto_oneof=variants",
+			inputFiles: map[string][]string{
+				"test41": {"./testdata/test41/strict.proto"},
+			},
+			wantFiles: []string{"test42/openapiv3.yaml"},
+		},
+		{
+			name:       "Test Any fields with known types are validated against them",
+			id:         "test43",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,int_native=true",
+			inputFiles: map[string][]string{
+				"test43": {"./testdata/test43/any.proto"},
+			},
+			wantFiles: []string{"test43/openapiv3.yaml"},
+		},
+		{
+			name:       "Test Any fields with known types have a discriminator mapping with refs",
+			id:         "test44",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,int_native=true,use_ref=true,ref_mode=inline_nested",
+			inputFiles: map[string][]string{
+				"test43": {"./testdata/test43/any.proto"},
+			},
+			wantFiles: []string{"test44/openapiv3.yaml"},
+		},
+		{
+			name:       "Test Any fields with known types accept @type in strict mode",
+			id:         "test45",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,int_native=true,use_ref=true,ref_mode=inline_nested,strict=true,proto_oneof=variants,additional_empty_schema=test43.RateLimit",
+			inputFiles: map[string][]string{
+				"test43": {"./testdata/test43/any.proto"},
+			},
+			wantFiles: []string{"test45/openapiv3.yaml"},
+		},
+		{
+			name:       "Test google.api.field_behavior adds no validation rule with disable_kube_markers",
+			id:         "test46",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,disable_kube_markers=true",
+			inputFiles: map[string][]string{
+				"test35": {"./testdata/test35/behavior.proto"},
+			},
+			wantFiles: []string{"test46/openapiv3.yaml"},
+		},
+		{
+			name:       "Test map keys can't be validated with disable_kube_markers",
+			id:         "test47",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,validate_map_keys=true,disable_kube_markers=true",
+			inputFiles: map[string][]string{
+				"test40": {"./testdata/test40/maps.proto"},
+			},
+			wantErr: "validate_map_keys adds Kubernetes validation rules, which disable_kube_markers=true omits",
+		},
+		{
+			name:       "Test numeric rules apply to the number alternative of protojson numbers",
+			id:         "test48",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,protojson_numbers=true",
+			inputFiles: map[string][]string{
+				"test26": {"./testdata/test26/wkt.proto"},
+			},
+			wantFiles: []string{"test48/openapiv3.yaml"},
+		},
+		{
+			name:       "Test recursive Any fields in strict mode are truncated instead of referenced",
+			id:         "test49",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,strict=true,recursion=ref",
+			inputFiles: map[string][]string{
+				"test49": {"./testdata/test49/recursive_any.proto"},
+			},
+			wantFiles: []string{"test49/openapiv3.yaml"},
+		},
+		{
+			name:       "Test patterns replace the components of top-level messages",
+			id:         "test50",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,multiline_description=true,additional_empty_schema=test40.*",
+			inputFiles: map[string][]string{
+				"test40": {"./testdata/test40/maps.proto"},
+			},
+			wantFiles: []string{"test50/openapiv3.yaml"},
+		},
+		{
+			name:       "Test deprecated fields, messages and enums have no note without descriptions",
+			id:         "test51",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,include_description=false,deprecation=mark,deprecation_warning=true",
+			inputFiles: map[string][]string{
+				"test33": {"./testdata/test33/deprecated.proto"},
+			},
+			wantFiles: []string{"test51/openapiv3.yaml"},
+		},
+		{
+			name:       "Test visible fields can't reference excluded types",
+			id:         "test52",
+			perPackage: false,
+			genOpts:    "yaml=true,single_file=true,exclude_hidden=true",
+			inputFiles: map[string][]string{
+				"test52": {"./testdata/test52/excluded.proto"},
+			},
+			wantErr: "field test52.Msg.secret references excluded type test52.Secret",
+		},
 	}
 
 	for _, tc := range testcases {
@@ -153,6 +575,15 @@ func TestOpenAPIGeneration(t *testing.T) {
 			}
 			defer os.RemoveAll(tempDir)
 
+			if tc.wantErr != "" {
+				args := []string{"-Itestdata", "--openapi_out=" + tc.genOpts + ":" + tempDir}
+				for _, files := range tc.inputFiles {
+					args = append(args, files...)
+				}
+				protocOpenAPIError(t, args, tc.wantErr)
+				return
+			}
+
 			if tc.perPackage {
 				for _, files := range tc.inputFiles {
 					args := []string{"-Itestdata", "--openapi_out=" + tc.genOpts + ":" + tempDir}
@@ -214,6 +645,20 @@ func protocOpenAPI(t *testing.T, args []string) {
 	}
 }
 
+// protocOpenAPIError runs protoc with the plugin and checks that the generation fails with the given error.
+func protocOpenAPIError(t *testing.T, args []string, wantErr string) {
+	cmd := exec.Command("protoc", "--plugin=protoc-gen-openapi="+os.Args[0])
+	cmd.Args = append(cmd.Args, args...)
+	cmd.Env = append(os.Environ(), "RUN_AS_PROTOC_GEN_OPENAPI=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("protoc succeeded, want error %q", wantErr)
+	}
+	if !strings.Contains(string(out), wantErr) {
+		t.Fatalf("protoc failed with %q, want error %q", string(out), wantErr)
+	}
+}
+
 func init() {
 	// when "RUN_AS_PROTOC_GEN_OPENAPI" is set, we use the protoc-gen-openapi directly
 	// for the test scenarios.
@@ -16,6 +16,7 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/solo-io/protoc-gen-openapi/pkg/protocgen"
@@ -51,12 +52,35 @@ func generate(request pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRes
 	includeDescription := true
 	multilineDescription := false
 	enumAsIntOrString := false
-	protoOneof := false
+	enumIntValues := false
+	validateMapKeys := false
+	strict := false
+	enumConfiguration := &EnumConfiguration{
+		Aliases:    EnumAliasKeep,
+		Deprecated: DeprecatedEnumValueKeep,
+	}
+	protoOneof := &ProtoOneofConfiguration{
+		Mode: ProtoOneofNone,
+	}
 	intNative := false
+	protoJSONNumbers := false
 	disableKubeMarkers := false
+	excludeHidden := false
+	stabilityExtension := false
+	recursion := RecursionTruncate
+	recursionDepth := 0
+	refMode := RefMapValues
+	externalRef := ExternalRefNone
+	fieldNaming := FieldNamingJSON
+
+	deprecationConfiguration := &DeprecationConfiguration{
+		Mode: DeprecationNone,
+	}
 
 	var messagesWithEmptySchema []string
+	var schemaOverrides SchemaOverrides
 	var ignoredKubeMarkerSubstrings []string
+	var classes []string
 
 	p := extractParams(request.GetParameter())
 	for k, v := range p {
@@ -120,13 +144,24 @@ func generate(request pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRes
 				return nil, fmt.Errorf("unknown value '%s' for enum_as_int_or_string", v)
 			}
 		} else if k == "proto_oneof" {
+			switch strings.ToLower(v) {
+			case string(ProtoOneofRequired):
+				protoOneof.Mode = ProtoOneofRequired
+			case string(ProtoOneofNone):
+				protoOneof.Mode = ProtoOneofNone
+			case string(ProtoOneofVariants):
+				protoOneof.Mode = ProtoOneofVariants
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for proto_oneof", v)
+			}
+		} else if k == "oneof_name_extension" {
 			switch strings.ToLower(v) {
 			case "true":
-				protoOneof = true
+				protoOneof.NameExtension = true
 			case "false":
-				protoOneof = false
+				protoOneof.NameExtension = false
 			default:
-				return nil, fmt.Errorf("unknown value '%s' for proto_oneof", v)
+				return nil, fmt.Errorf("unknown value '%s' for oneof_name_extension", v)
 			}
 		} else if k == "int_native" {
 			switch strings.ToLower(v) {
@@ -137,8 +172,99 @@ func generate(request pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRes
 			default:
 				return nil, fmt.Errorf("unknown value '%s' for int_native", v)
 			}
+		} else if k == "overrides" {
+			overrides, err := LoadSchemaOverrides(v)
+			if err != nil {
+				return nil, err
+			}
+			schemaOverrides = overrides
+		} else if k == "enum_int_values" {
+			switch strings.ToLower(v) {
+			case "true":
+				enumIntValues = true
+			case "false":
+				enumIntValues = false
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for enum_int_values", v)
+			}
+		} else if k == "validate_map_keys" {
+			switch strings.ToLower(v) {
+			case "true":
+				validateMapKeys = true
+			case "false":
+				validateMapKeys = false
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for validate_map_keys", v)
+			}
+		} else if k == "strict" {
+			switch strings.ToLower(v) {
+			case "true":
+				strict = true
+			case "false":
+				strict = false
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for strict", v)
+			}
+		} else if k == "exclude_unspecified" {
+			switch strings.ToLower(v) {
+			case "true":
+				enumConfiguration.ExcludeUnspecified = true
+			case "false":
+				enumConfiguration.ExcludeUnspecified = false
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for exclude_unspecified", v)
+			}
+		} else if k == "enum_aliases" {
+			switch strings.ToLower(v) {
+			case string(EnumAliasKeep):
+				enumConfiguration.Aliases = EnumAliasKeep
+			case string(EnumAliasCanonical):
+				enumConfiguration.Aliases = EnumAliasCanonical
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for enum_aliases", v)
+			}
+		} else if k == "deprecated_enum_values" {
+			switch strings.ToLower(v) {
+			case string(DeprecatedEnumValueKeep):
+				enumConfiguration.Deprecated = DeprecatedEnumValueKeep
+			case string(DeprecatedEnumValueDrop):
+				enumConfiguration.Deprecated = DeprecatedEnumValueDrop
+			case string(DeprecatedEnumValueAnnotate):
+				enumConfiguration.Deprecated = DeprecatedEnumValueAnnotate
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for deprecated_enum_values", v)
+			}
+		} else if k == "deprecation" {
+			switch strings.ToLower(v) {
+			case string(DeprecationNone):
+				deprecationConfiguration.Mode = DeprecationNone
+			case string(DeprecationMark):
+				deprecationConfiguration.Mode = DeprecationMark
+			case string(DeprecationNote):
+				deprecationConfiguration.Mode = DeprecationNote
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for deprecation", v)
+			}
+		} else if k == "deprecation_warning" {
+			switch strings.ToLower(v) {
+			case "true":
+				deprecationConfiguration.Warning = true
+			case "false":
+				deprecationConfiguration.Warning = false
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for deprecation_warning", v)
+			}
 		} else if k == "additional_empty_schema" {
 			messagesWithEmptySchema = strings.Split(v, "+")
+		} else if k == "protojson_numbers" {
+			switch strings.ToLower(v) {
+			case "true":
+				protoJSONNumbers = true
+			case "false":
+				protoJSONNumbers = false
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for protojson_numbers", v)
+			}
 		} else if k == "disable_kube_markers" {
 			switch strings.ToLower(v) {
 			case "true":
@@ -152,6 +278,76 @@ func generate(request pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRes
 			if len(v) > 0 {
 				ignoredKubeMarkerSubstrings = strings.Split(v, "+")
 			}
+		} else if k == "exclude_hidden" {
+			switch strings.ToLower(v) {
+			case "true":
+				excludeHidden = true
+			case "false":
+				excludeHidden = false
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for exclude_hidden", v)
+			}
+		} else if k == "classes" {
+			if len(v) > 0 {
+				classes = strings.Split(v, "+")
+			}
+		} else if k == "stability_extension" {
+			switch strings.ToLower(v) {
+			case "true":
+				stabilityExtension = true
+			case "false":
+				stabilityExtension = false
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for stability_extension", v)
+			}
+		} else if k == "recursion" {
+			switch strings.ToLower(v) {
+			case string(RecursionRef):
+				recursion = RecursionRef
+			case string(RecursionTruncate):
+				recursion = RecursionTruncate
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for recursion", v)
+			}
+		} else if k == "ref_mode" {
+			switch strings.ToLower(v) {
+			case string(RefMapValues):
+				refMode = RefMapValues
+			case string(RefInlineNested):
+				refMode = RefInlineNested
+			case string(RefNested):
+				refMode = RefNested
+			case string(RefAll):
+				refMode = RefAll
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for ref_mode", v)
+			}
+		} else if k == "external_ref" {
+			switch strings.ToLower(v) {
+			case string(ExternalRefFile):
+				externalRef = ExternalRefFile
+			case string(ExternalRefLocation):
+				externalRef = ExternalRefLocation
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for external_ref", v)
+			}
+		} else if k == "field_naming" {
+			switch strings.ToLower(v) {
+			case string(FieldNamingJSON):
+				fieldNaming = FieldNamingJSON
+			case string(FieldNamingProto):
+				fieldNaming = FieldNamingProto
+			case string(FieldNamingBoth):
+				fieldNaming = FieldNamingBoth
+			default:
+				return nil, fmt.Errorf("unknown value '%s' for field_naming", v)
+			}
+		} else if k == "recursion_depth" {
+			depth, err := strconv.Atoi(v)
+			if err != nil || depth < 0 {
+				return nil, fmt.Errorf("unknown value '%s' for recursion_depth", v)
+			}
+			recursionDepth = depth
 		} else {
 			return nil, fmt.Errorf("unknown argument '%s' specified", k)
 		}
@@ -161,6 +357,10 @@ func generate(request pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRes
 		return nil, fmt.Errorf("multiline_description is only supported when yaml=true")
 	}
 
+	if validateMapKeys && disableKubeMarkers {
+		return nil, fmt.Errorf("validate_map_keys adds Kubernetes validation rules, which disable_kube_markers=true omits")
+	}
+
 	m := protomodel.NewModel(&request, perFile)
 
 	filesToGen := make(map[*protomodel.FileDescriptor]bool)
@@ -177,20 +377,37 @@ func generate(request pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRes
 		MultilineDescription:       multilineDescription,
 	}
 
-	g := newOpenAPIGenerator(
-		m,
-		perFile,
-		singleFile,
-		yaml,
-		useRef,
-		descriptionConfiguration,
-		enumAsIntOrString,
-		messagesWithEmptySchema,
-		protoOneof,
-		intNative,
-		disableKubeMarkers,
-		ignoredKubeMarkerSubstrings,
-	)
+	recursionConfiguration := &RecursionConfiguration{
+		Mode:  recursion,
+		Depth: recursionDepth,
+	}
+
+	g := newOpenAPIGenerator(m, GeneratorOptions{
+		PerFile:                 perFile,
+		SingleFile:              singleFile,
+		YAML:                    yaml,
+		UseRef:                  useRef,
+		Description:             descriptionConfiguration,
+		EnumAsIntOrString:       enumAsIntOrString,
+		MessagesWithEmptySchema: messagesWithEmptySchema,
+		ProtoOneof:              protoOneof,
+		IntNative:               intNative,
+		DisableKubeMarkers:      disableKubeMarkers,
+		IgnoredKubeMarkers:      ignoredKubeMarkerSubstrings,
+		ExcludeHidden:           excludeHidden,
+		ClassFilter:             NewClassFilter(classes, stabilityExtension),
+		Recursion:               recursionConfiguration,
+		RefMode:                 refMode,
+		ExternalRef:             externalRef,
+		FieldNaming:             fieldNaming,
+		ProtoJSONNumbers:        protoJSONNumbers,
+		SchemaOverrides:         schemaOverrides,
+		EnumIntValues:           enumIntValues,
+		Enums:                   enumConfiguration,
+		Deprecation:             deprecationConfiguration,
+		ValidateMapKeys:         validateMapKeys,
+		Strict:                  strict,
+	})
 	return g.generateOutput(filesToGen)
 }
 
@@ -23,6 +23,8 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -30,13 +32,12 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/pluginpb"
-	kubemarkers "sigs.k8s.io/controller-tools/pkg/markers"
 
 	"github.com/solo-io/protoc-gen-openapi/pkg/markers"
 	"github.com/solo-io/protoc-gen-openapi/pkg/protomodel"
 )
 
-var descriptionExclusionMarkers = []string{"$hide_from_docs", "$hide", "@exclude"}
+var descriptionExclusionMarkers = []string{"$hide_from_docs", "$hide", "@exclude", "$unspecified", "$any_types"}
 
 // Some special types with predefined schemas.
 // This is to catch cases where solo apis contain recursive definitions
@@ -46,7 +47,11 @@ var specialSoloTypes = map[string]openapi3.Schema{
 	"core.solo.io.Metadata": {
 		Type: &openapi3.Types{openapi3.TypeObject},
 	},
-	"google.protobuf.ListValue": *openapi3.NewArraySchema().WithItems(openapi3.NewObjectSchema()),
+	"google.protobuf.ListValue": *openapi3.NewArraySchema().WithItems(&openapi3.Schema{
+		Extensions: map[string]interface{}{
+			"x-kubernetes-preserve-unknown-fields": true,
+		},
+	}),
 	"google.protobuf.Struct": {
 		Type:       &openapi3.Types{openapi3.TypeObject},
 		Properties: make(map[string]*openapi3.SchemaRef),
@@ -75,9 +80,32 @@ var specialSoloTypes = map[string]openapi3.Schema{
 	"google.protobuf.UInt32Value": *openapi3.NewIntegerSchema().WithNullable().WithMin(0).WithMax(math.MaxUint32),
 	"google.protobuf.UInt64Value": *openapi3.NewIntegerSchema().WithNullable().WithMin(0).WithMax(math.MaxUint64),
 	"google.protobuf.FloatValue":  *openapi3.NewFloat64Schema().WithNullable(),
-	"google.protobuf.Duration":    *openapi3.NewStringSchema(),
+	"google.protobuf.BytesValue":  *openapi3.NewBytesSchema().WithNullable(),
+	"google.protobuf.Duration":    *openapi3.NewStringSchema().WithPattern(`^-?\d+(\.\d+)?s$`),
 	"google.protobuf.Empty":       *openapi3.NewObjectSchema().WithMaxProperties(0),
 	"google.protobuf.Timestamp":   *openapi3.NewStringSchema().WithFormat("date-time"),
+	// a comma-separated list of lowerCamelCase paths
+	"google.protobuf.FieldMask": *openapi3.NewStringSchema().
+		WithPattern(`^([a-z][a-zA-Z0-9]*(\.[a-z][a-zA-Z0-9]*)*(,[a-z][a-zA-Z0-9]*(\.[a-z][a-zA-Z0-9]*)*)*)?$`),
+}
+
+// The range of the fields of common types from `google.type`, applied as validation rules alongside
+// the rules found in the comments of the fields.
+var commonTypeFieldRules = map[string][]string{
+	"google.type.Date.year":           {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=9999"},
+	"google.type.Date.month":          {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=12"},
+	"google.type.Date.day":            {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=31"},
+	"google.type.TimeOfDay.hours":     {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=24"},
+	"google.type.TimeOfDay.minutes":   {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=59"},
+	"google.type.TimeOfDay.seconds":   {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=60"},
+	"google.type.TimeOfDay.nanos":     {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=999999999"},
+	"google.type.Money.currency_code": {"+kubebuilder:validation:Pattern=`^[A-Z]{3}$`"},
+	"google.type.Money.nanos":         {"+kubebuilder:validation:Minimum=-999999999", "+kubebuilder:validation:Maximum=999999999"},
+	"google.type.LatLng.latitude":     {"+kubebuilder:validation:Minimum=-90", "+kubebuilder:validation:Maximum=90"},
+	"google.type.LatLng.longitude":    {"+kubebuilder:validation:Minimum=-180", "+kubebuilder:validation:Maximum=180"},
+	"google.type.Color.red":           {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=1"},
+	"google.type.Color.green":         {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=1"},
+	"google.type.Color.blue":          {"+kubebuilder:validation:Minimum=0", "+kubebuilder:validation:Maximum=1"},
 }
 
 type openapiGenerator struct {
@@ -93,6 +121,7 @@ type openapiGenerator struct {
 	currentFrontMatterProvider *protomodel.FileDescriptor
 
 	messages map[string]*protomodel.MessageDescriptor
+	enums    map[string]*protomodel.EnumDescriptor
 
 	// @solo.io customizations to limit length of generated descriptions
 	descriptionConfiguration *DescriptionConfiguration
@@ -101,25 +130,107 @@ type openapiGenerator struct {
 	// we need to support this since some controllers marshal enums as integers and others as strings
 	enumAsIntOrString bool
 
+	// If set to true, the schemas of enums list the numbers of their values alongside the names, and the
+	// names and descriptions of each value in the `x-enum-varnames` and `x-enum-descriptions` extensions
+	enumIntValues bool
+
+	// which values of the enums are listed in their schemas
+	enumConfiguration *EnumConfiguration
+
+	// how deprecated fields, messages and enums are marked in their schemas
+	deprecationConfiguration *DeprecationConfiguration
+
+	// If set to true, the keys of maps with integer or boolean keys are restricted to their JSON form
+	// with `x-kubernetes-validations` rules
+	validateMapKeys bool
+
+	// If set to true, the schemas of messages don't accept unknown properties
+	strict bool
+
 	// @solo.io customizations to define schemas for certain messages
 	customSchemasByMessageName map[string]openapi3.Schema
 
-	// If set to true, OpenAPI schema will include schema to emulate behavior of protobuf oneof fields
-	protoOneof bool
+	// user-supplied schema fragments, which replace the schema of messages and patch the schema of fields
+	schemaOverrides SchemaOverrides
+
+	// the names of customSchemasByMessageName which are patterns matching many messages
+	customSchemaPatterns []*namePattern
+
+	// the user-supplied names and patterns, with the option they come from, and the ones which matched
+	// a message or field, so that stale configuration can be reported
+	userNames    map[string]string
+	matchedNames map[string]bool
+
+	// How the OpenAPI schema emulates the behavior of protobuf oneof fields
+	protoOneof *ProtoOneofConfiguration
 
 	// If set to true, native OpenAPI integer scehmas will be used for integer types instead of Solo wrappers
 	// that add Kubernetes extension headers to the schema to treat int as strings.
 	intNative bool
 
+	// If set to true, 64-bit integers and floating point numbers are modeled as encoded by protojson, which
+	// emits 64-bit integers as strings and the special float values as "NaN", "Infinity" and "-Infinity".
+	protoJSONNumbers bool
+
 	markerRegistry *markers.Registry
 
 	// If set to true, kubebuilder markers and validations such as PreserveUnknownFields, MinItems, default, and all CEL rules will be omitted from the OpenAPI schema.
 	// The Type and Required markers will be maintained.
 	disableKubeMarkers bool
 
-	// when set, this list of substrings will be used to identify kubebuilder markers to ignore. When multiple are
-	// supplied, this will function as a logical OR i.e. any rule which contains a provided substring will be ignored
-	ignoredKubeMarkerSubstrings []string
+	// when set, this regexp built from a list of substrings will be used to identify kubebuilder markers to ignore.
+	// When multiple are supplied, this will function as a logical OR i.e. any rule which contains a provided
+	// substring will be ignored
+	ignoredKubeMarkersRegexp *regexp.Regexp
+
+	// the description and markers parsed from the leading comments of each descriptor, parsed once
+	// and shared by everything that renders a descriptor
+	comments map[protomodel.CoreDesc]*descComments
+
+	// If set to true, fields, enum values, messages and enums marked as hidden with `$hide_from_docs` or
+	// `[#not-implemented-hide:]` will be omitted from the OpenAPI schema.
+	excludeHidden bool
+
+	// filters fields, enum values, messages and enums by the class set with the `$class:` comment annotation
+	classFilter *ClassFilter
+
+	// how recursive messages are rendered once a cycle is detected in the message graph
+	recursionConfiguration *RecursionConfiguration
+
+	// which messages and enums are referenced with `$ref` when useRef is set
+	refMode RefMode
+
+	// how messages and enums defined outside of the current output document are referenced
+	externalRef ExternalRefMode
+
+	// which names of the fields are used for the properties of the schemas
+	fieldNaming FieldNaming
+
+	// the files the output is generated for
+	filesToGen map[*protomodel.FileDescriptor]bool
+
+	// transient state used to detect cycles while messages are generated
+	messageStack   []*protomodel.MessageDescriptor
+	reportedCycles map[string]bool
+	recursionCuts  int
+
+	// messages and enums referenced with `$ref` from the current output document, whose
+	// components still need to be generated
+	pendingComponents map[string]protomodel.CoreDesc
+
+	// schemas built for the current output document, which are reused every time the message or
+	// enum is referenced again
+	messageSchemas map[*protomodel.MessageDescriptor]*openapi3.Schema
+	enumSchemas    map[*protomodel.EnumDescriptor]*openapi3.Schema
+}
+
+// descComments is the intermediate representation of the leading comments of a descriptor.
+type descComments struct {
+	// the description rendered from the comments
+	description string
+
+	// the kubebuilder markers found in the comments, parsed for the target of the descriptor
+	markers markers.Markers
 }
 
 type DescriptionConfiguration struct {
@@ -130,38 +241,414 @@ type DescriptionConfiguration struct {
 	MultilineDescription bool
 }
 
-func newOpenAPIGenerator(
-	model *protomodel.Model,
-	perFile bool,
-	singleFile bool,
-	yaml bool,
-	useRef bool,
-	descriptionConfiguration *DescriptionConfiguration,
-	enumAsIntOrString bool,
-	messagesWithEmptySchema []string,
-	protoOneof bool,
-	intNative bool,
-	disableKubeMarkers bool,
-	ignoredKubeMarkers []string,
-) *openapiGenerator {
+type ClassFilter struct {
+	// When not empty, only descriptors with one of these classes are included in the generated schema
+	included map[string]bool
+
+	// Descriptors with one of these classes are omitted from the generated schema
+	excluded map[string]bool
+
+	// Whether or not to tag the schemas of classified descriptors with an `x-stability` extension
+	stabilityExtension bool
+}
+
+type RecursionMode string
+
+const (
+	// RecursionRef references the component of a recursive message with `$ref`
+	RecursionRef RecursionMode = "ref"
+
+	// RecursionTruncate expands a recursive message up to a depth and then replaces it with an
+	// object which preserves unknown fields
+	RecursionTruncate RecursionMode = "truncate"
+)
+
+type EnumAliasMode string
+
+const (
+	// EnumAliasKeep lists every name of the values, including aliases
+	EnumAliasKeep EnumAliasMode = "keep"
+
+	// EnumAliasCanonical only lists the first name of each number, which protojson emits
+	EnumAliasCanonical EnumAliasMode = "canonical"
+)
+
+type DeprecatedEnumValueMode string
+
+const (
+	// DeprecatedEnumValueKeep lists deprecated values like any other value
+	DeprecatedEnumValueKeep DeprecatedEnumValueMode = "keep"
+
+	// DeprecatedEnumValueDrop omits deprecated values
+	DeprecatedEnumValueDrop DeprecatedEnumValueMode = "drop"
+
+	// DeprecatedEnumValueAnnotate lists deprecated values and their names in an `x-enum-deprecated` extension
+	DeprecatedEnumValueAnnotate DeprecatedEnumValueMode = "annotate"
+)
+
+type EnumConfiguration struct {
+	// Whether or not to omit the zero value sentinels named `*_UNSPECIFIED` or annotated with `$unspecified`
+	ExcludeUnspecified bool
+
+	// How the aliases of enum values are listed
+	Aliases EnumAliasMode
+
+	// How deprecated enum values are listed
+	Deprecated DeprecatedEnumValueMode
+}
+
+type ProtoOneofMode string
+
+const (
+	// ProtoOneofNone doesn't restrict the members of a oneof
+	ProtoOneofNone ProtoOneofMode = "false"
+
+	// ProtoOneofRequired allows at most one member of a oneof through combinations of `required`
+	ProtoOneofRequired ProtoOneofMode = "true"
+
+	// ProtoOneofVariants lists an object schema per member of a oneof, along with the other fields of the
+	// message, so that clients see each alternative explicitly
+	ProtoOneofVariants ProtoOneofMode = "variants"
+)
+
+type ProtoOneofConfiguration struct {
+	// How oneofs are emulated
+	Mode ProtoOneofMode
+
+	// Whether or not to add an `x-oneof-name` extension with the name of the oneof to each variant
+	NameExtension bool
+}
+
+type DeprecationMode string
+
+const (
+	// DeprecationNone ignores the `deprecated` option
+	DeprecationNone DeprecationMode = "none"
+
+	// DeprecationMark sets `deprecated: true` on the schema and adds a deprecation note to its description
+	DeprecationMark DeprecationMode = "mark"
+
+	// DeprecationNote only adds a deprecation note to the description, since Kubernetes rejects
+	// `deprecated` in the schema of CRDs
+	DeprecationNote DeprecationMode = "note"
+)
+
+type DeprecationConfiguration struct {
+	// How deprecated descriptors are marked
+	Mode DeprecationMode
+
+	// Whether or not to add an `x-deprecation-warning` extension to the schema of deprecated descriptors
+	Warning bool
+}
+
+type RefMode string
+
+const (
+	// RefMapValues only references the top-level messages of the output document which are the values
+	// of maps, and inlines every field
+	RefMapValues RefMode = "map_values"
+
+	// RefInlineNested references top-level messages and inlines nested messages and enums
+	RefInlineNested RefMode = "inline_nested"
+
+	// RefNested also references nested messages and all enums defined in the output document
+	RefNested RefMode = "ref_nested"
+
+	// RefAll references every message and enum, adding components for the ones defined
+	// outside of the output document
+	RefAll RefMode = "ref_all"
+)
+
+type ExternalRefMode string
+
+const (
+	// ExternalRefNone inlines messages and enums defined outside of the output document
+	ExternalRefNone ExternalRefMode = ""
+
+	// ExternalRefFile references the component in the output document generated for the
+	// package, or file, of the message or enum
+	ExternalRefFile ExternalRefMode = "file"
+
+	// ExternalRefLocation references the component at the `$location:` of the file of the
+	// message or enum
+	ExternalRefLocation ExternalRefMode = "location"
+)
+
+type FieldNaming string
+
+const (
+	// FieldNamingJSON uses the lowerCamelCase JSON name of the fields
+	FieldNamingJSON FieldNaming = "json"
+
+	// FieldNamingProto uses the original proto name of the fields
+	FieldNamingProto FieldNaming = "proto"
+
+	// FieldNamingBoth accepts either name of the fields, but not both at once
+	FieldNamingBoth FieldNaming = "both"
+)
+
+// SchemaOverrides maps the fully qualified names of messages and fields to OpenAPI schema fragments.
+// The fragment of a message replaces its schema, while the fragment of a field is merged into the
+// schema generated for the field as a JSON merge patch.
+type SchemaOverrides map[string]map[string]interface{}
+
+// LoadSchemaOverrides reads the schema overrides from a YAML file.
+func LoadSchemaOverrides(filename string) (SchemaOverrides, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read overrides file %s: %v", filename, err)
+	}
+	var overrides SchemaOverrides
+	if err := yaml.Unmarshal(b, &overrides); err != nil {
+		return nil, fmt.Errorf("unable to parse overrides file %s: %v", filename, err)
+	}
+	for name := range overrides {
+		if _, err := overrides.schema(name); err != nil {
+			return nil, fmt.Errorf("invalid schema for %s in overrides file %s: %v", name, filename, err)
+		}
+	}
+	return overrides, nil
+}
+
+func (o SchemaOverrides) schema(name string) (*openapi3.Schema, error) {
+	b, err := json.Marshal(o[name])
+	if err != nil {
+		return nil, err
+	}
+	schema := openapi3.NewSchema()
+	if err := json.Unmarshal(b, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+type RecursionConfiguration struct {
+	// How recursive messages are rendered
+	Mode RecursionMode
+
+	// The number of times a recursive message is expanded again before being truncated
+	Depth int
+}
+
+// GeneratorOptions configures the generator. Unset modes and configurations take the defaults of the
+// plugin options.
+type GeneratorOptions struct {
+	// Whether or not to generate one output document per file instead of per package
+	PerFile bool
+
+	// Whether or not to generate a single output document for all the files
+	SingleFile bool
+
+	// Whether or not the output is in YAML instead of JSON
+	YAML bool
+
+	// Whether or not to reference other schemas with `$ref`
+	UseRef bool
+
+	// Whether and how descriptions are included in the schemas
+	Description *DescriptionConfiguration
+
+	// Whether or not enums have the `x-kubernetes-int-or-string` extension
+	EnumAsIntOrString bool
+
+	// The names and patterns of the messages whose schema accepts any value
+	MessagesWithEmptySchema []string
+
+	// How the members of oneofs are restricted
+	ProtoOneof *ProtoOneofConfiguration
+
+	// Whether or not to use native integer schemas instead of the Kubernetes int-or-string extension
+	IntNative bool
+
+	// Whether or not to omit the kubebuilder markers and Kubernetes validation rules
+	DisableKubeMarkers bool
+
+	// The substrings of the kubebuilder markers to ignore
+	IgnoredKubeMarkers []string
+
+	// Whether or not to omit the fields, messages and enums marked with `+hidden`
+	ExcludeHidden bool
+
+	// Which classes of descriptors are included in the generated schema
+	ClassFilter *ClassFilter
+
+	// How recursive messages are rendered
+	Recursion *RecursionConfiguration
+
+	// Which messages and enums are referenced with `$ref` instead of inlined
+	RefMode RefMode
+
+	// How messages and enums of packages outside of the generated files are referenced
+	ExternalRef ExternalRefMode
+
+	// Which names of the fields are accepted as properties
+	FieldNaming FieldNaming
+
+	// Whether or not to model 64-bit integers and floating point numbers as encoded by protojson
+	ProtoJSONNumbers bool
+
+	// User-supplied schema fragments for messages and fields
+	SchemaOverrides SchemaOverrides
+
+	// Whether or not to list the numbers, names and descriptions of the values of enums
+	EnumIntValues bool
+
+	// Which values of the enums are listed in their schemas
+	Enums *EnumConfiguration
+
+	// How deprecated fields, messages and enums are marked in their schemas
+	Deprecation *DeprecationConfiguration
+
+	// Whether or not to restrict the keys of maps with integer or boolean keys to their JSON form
+	ValidateMapKeys bool
+
+	// Whether or not the schemas of messages reject unknown properties
+	Strict bool
+}
+
+// withDefaults returns a copy of the options where the unset modes and configurations have their
+// default value.
+func (o GeneratorOptions) withDefaults() GeneratorOptions {
+	if o.Description == nil {
+		o.Description = &DescriptionConfiguration{IncludeDescriptionInSchema: true}
+	}
+	if o.ProtoOneof == nil {
+		o.ProtoOneof = &ProtoOneofConfiguration{Mode: ProtoOneofNone}
+	}
+	if o.ClassFilter == nil {
+		o.ClassFilter = NewClassFilter(nil, false)
+	}
+	if o.Recursion == nil {
+		o.Recursion = &RecursionConfiguration{Mode: RecursionTruncate}
+	}
+	if o.RefMode == "" {
+		o.RefMode = RefMapValues
+	}
+	if o.FieldNaming == "" {
+		o.FieldNaming = FieldNamingJSON
+	}
+	if o.Enums == nil {
+		o.Enums = &EnumConfiguration{Aliases: EnumAliasKeep, Deprecated: DeprecatedEnumValueKeep}
+	}
+	if o.Deprecation == nil {
+		o.Deprecation = &DeprecationConfiguration{Mode: DeprecationNone}
+	}
+	return o
+}
+
+// namePattern matches fully qualified names, where `*` matches any part of a single name segment and
+// `**` matches any number of segments, e.g. `envoy.config.**` or `**.Metadata`.
+type namePattern struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func isNamePattern(name string) bool {
+	return strings.Contains(name, "*")
+}
+
+// newNamePatterns returns the patterns among the keys of a map, sorted so that the longest pattern,
+// which takes precedence when several patterns match a name, comes last.
+func newNamePatterns(names map[string]openapi3.Schema) []*namePattern {
+	var patterns []*namePattern
+	for name := range names {
+		if !isNamePattern(name) {
+			continue
+		}
+		var sb strings.Builder
+		sb.WriteString("^")
+		for i := 0; i < len(name); i++ {
+			if strings.HasPrefix(name[i:], "**") {
+				sb.WriteString(".*")
+				i++
+			} else if name[i] == '*' {
+				sb.WriteString("[^.]*")
+			} else {
+				sb.WriteString(regexp.QuoteMeta(name[i : i+1]))
+			}
+		}
+		sb.WriteString("$")
+		patterns = append(patterns, &namePattern{pattern: name, re: regexp.MustCompile(sb.String())})
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i].pattern) != len(patterns[j].pattern) {
+			return len(patterns[i].pattern) < len(patterns[j].pattern)
+		}
+		return patterns[i].pattern < patterns[j].pattern
+	})
+	return patterns
+}
+
+// NewClassFilter builds a ClassFilter from a list of class names. Names prefixed with `-` are
+// excluded, all others are the only classes included.
+func NewClassFilter(classes []string, stabilityExtension bool) *ClassFilter {
+	f := &ClassFilter{
+		included:           make(map[string]bool),
+		excluded:           make(map[string]bool),
+		stabilityExtension: stabilityExtension,
+	}
+	for _, cl := range classes {
+		if strings.HasPrefix(cl, "-") {
+			f.excluded[cl[1:]] = true
+		} else if cl != "" {
+			f.included[cl] = true
+		}
+	}
+	return f
+}
+
+func newOpenAPIGenerator(model *protomodel.Model, options GeneratorOptions) *openapiGenerator {
 	mRegistry, err := markers.NewRegistry()
 	if err != nil {
 		log.Panicf("error initializing marker registry: %v", err)
 	}
+	options = options.withDefaults()
+	var ignoredKubeMarkersRegexp *regexp.Regexp
+	if len(options.IgnoredKubeMarkers) > 0 {
+		ignoredKubeMarkersRegexp = regexp.MustCompile(
+			fmt.Sprintf("(?:%s)", strings.Join(options.IgnoredKubeMarkers, "|")),
+		)
+	}
+	customSchemas := buildCustomSchemasByMessageName(options.MessagesWithEmptySchema, options.SchemaOverrides)
+	userNames := make(map[string]string)
+	for _, name := range options.MessagesWithEmptySchema {
+		userNames[name] = "additional_empty_schema"
+	}
+	for name := range options.SchemaOverrides {
+		userNames[name] = "overrides"
+	}
 	return &openapiGenerator{
-		model:                       model,
-		perFile:                     perFile,
-		singleFile:                  singleFile,
-		yaml:                        yaml,
-		useRef:                      useRef,
-		descriptionConfiguration:    descriptionConfiguration,
-		enumAsIntOrString:           enumAsIntOrString,
-		customSchemasByMessageName:  buildCustomSchemasByMessageName(messagesWithEmptySchema),
-		protoOneof:                  protoOneof,
-		intNative:                   intNative,
-		markerRegistry:              mRegistry,
-		disableKubeMarkers:          disableKubeMarkers,
-		ignoredKubeMarkerSubstrings: ignoredKubeMarkers,
+		model:                      model,
+		perFile:                    options.PerFile,
+		singleFile:                 options.SingleFile,
+		yaml:                       options.YAML,
+		useRef:                     options.UseRef,
+		descriptionConfiguration:   options.Description,
+		enumAsIntOrString:          options.EnumAsIntOrString,
+		customSchemasByMessageName: customSchemas,
+		schemaOverrides:            options.SchemaOverrides,
+		customSchemaPatterns:       newNamePatterns(customSchemas),
+		userNames:                  userNames,
+		matchedNames:               make(map[string]bool),
+		protoOneof:                 options.ProtoOneof,
+		intNative:                  options.IntNative,
+		markerRegistry:             mRegistry,
+		disableKubeMarkers:         options.DisableKubeMarkers,
+		ignoredKubeMarkersRegexp:   ignoredKubeMarkersRegexp,
+		comments:                   make(map[protomodel.CoreDesc]*descComments),
+		excludeHidden:              options.ExcludeHidden,
+		classFilter:                options.ClassFilter,
+		recursionConfiguration:     options.Recursion,
+		refMode:                    options.RefMode,
+		externalRef:                options.ExternalRef,
+		fieldNaming:                options.FieldNaming,
+		protoJSONNumbers:           options.ProtoJSONNumbers,
+		enumIntValues:              options.EnumIntValues,
+		enumConfiguration:          options.Enums,
+		deprecationConfiguration:   options.Deprecation,
+		validateMapKeys:            options.ValidateMapKeys,
+		strict:                     options.Strict,
+		reportedCycles:             make(map[string]bool),
 	}
 }
 
@@ -170,7 +657,8 @@ func newOpenAPIGenerator(
 //  1. `specialSoloTypes`, a set of pre-defined schemas
 //  2. `messagesWithEmptySchema`, a list of messages that are injected at runtime that should contain
 //     and empty schema which accepts and preserves all fields
-func buildCustomSchemasByMessageName(messagesWithEmptySchema []string) map[string]openapi3.Schema {
+//  3. `schemaOverrides`, user-supplied schemas which replace the schema of messages
+func buildCustomSchemasByMessageName(messagesWithEmptySchema []string, schemaOverrides SchemaOverrides) map[string]openapi3.Schema {
 	schemasByMessageName := make(map[string]openapi3.Schema)
 
 	// Initialize the hard-coded values
@@ -180,21 +668,35 @@ func buildCustomSchemasByMessageName(messagesWithEmptySchema []string) map[strin
 
 	// Add the messages that were injected at runtime
 	for _, messageName := range messagesWithEmptySchema {
-		emptyMessage := openapi3.Schema{
-			Type:       &openapi3.Types{openapi3.TypeObject},
-			Properties: make(map[string]*openapi3.SchemaRef),
-			Extensions: map[string]interface{}{
-				"x-kubernetes-preserve-unknown-fields": true,
-			},
+		schemasByMessageName[messageName] = *newPreserveUnknownFieldsSchema()
+	}
+
+	// Add the user-supplied schemas. The overrides of fields never match the name of a message, and
+	// patterns only match messages.
+	for name := range schemaOverrides {
+		if schema, err := schemaOverrides.schema(name); err == nil {
+			schemasByMessageName[name] = *schema
 		}
-		schemasByMessageName[messageName] = emptyMessage
 	}
 
 	return schemasByMessageName
 }
 
 func (g *openapiGenerator) generateOutput(filesToGen map[*protomodel.FileDescriptor]bool) (*pluginpb.CodeGeneratorResponse, error) {
-	response := pluginpb.CodeGeneratorResponse{}
+	response := pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: proto.Uint64(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL |
+			pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)),
+		MinimumEdition: proto.Int32(int32(protomodel.MinimumEdition)),
+		MaximumEdition: proto.Int32(int32(protomodel.MaximumEdition)),
+	}
+
+	if err := g.validateExcludedReferences(filesToGen); err != nil {
+		return nil, err
+	}
+	if err := g.validateAnyTypes(); err != nil {
+		return nil, err
+	}
+	g.filesToGen = filesToGen
 
 	if g.singleFile {
 		g.generateSingleFileOutput(filesToGen, &response)
@@ -220,9 +722,53 @@ func (g *openapiGenerator) generateOutput(filesToGen map[*protomodel.FileDescrip
 		}
 	}
 
+	g.reportUnmatchedNames()
+
 	return &response, nil
 }
 
+// validateExcludedReferences ensures that no visible field references a message or enum that is
+// omitted from the output because it is hidden or filtered out by its class.
+func (g *openapiGenerator) validateExcludedReferences(filesToGen map[*protomodel.FileDescriptor]bool) error {
+	for file := range filesToGen {
+		for _, msg := range file.AllMessages {
+			if g.isExcluded(msg) {
+				continue
+			}
+			for _, field := range msg.Fields {
+				if g.isExcluded(field) || field.FieldType == nil {
+					continue
+				}
+				if g.isExcluded(field.FieldType) {
+					return fmt.Errorf("field %s references excluded type %s",
+						g.absoluteName(field), g.absoluteName(field.FieldType))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateAnyTypes ensures that the `$any_types:` annotations of the Any fields name known messages.
+// Messages of any file may be inlined in the output, so the fields of all the files are checked.
+func (g *openapiGenerator) validateAnyTypes() error {
+	for _, file := range g.model.AllFilesByName {
+		for _, msg := range file.AllMessages {
+			for _, field := range msg.Fields {
+				for _, name := range field.AnyTypes() {
+					if _, ok := g.model.AllDescByName["."+strings.TrimPrefix(name, ".")].(*protomodel.MessageDescriptor); !ok {
+						return fmt.Errorf("unknown message %s in the $any_types of %s, its file must be imported",
+							name, g.absoluteName(field))
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (g *openapiGenerator) getFileContents(file *protomodel.FileDescriptor,
 	messages map[string]*protomodel.MessageDescriptor,
 	enums map[string]*protomodel.EnumDescriptor,
@@ -252,16 +798,27 @@ func (g *openapiGenerator) generatePerFileOutput(filesToGen map[*protomodel.File
 			services := make(map[string]*protomodel.ServiceDescriptor)
 
 			g.getFileContents(file, messages, enums, services)
-			filename := path.Base(file.GetName())
-			extension := path.Ext(filename)
-			name := filename[0 : len(filename)-len(extension)]
 
-			rf := g.generateFile(name, file, messages, enums, services)
+			rf := g.generateFile(perFileOutputName(file), file, messages, enums, services)
 			response.File = append(response.File, &rf)
 		}
 	}
 }
 
+// perFileOutputName returns the name of the output document generated for a file in per-file mode.
+func perFileOutputName(file *protomodel.FileDescriptor) string {
+	filename := path.Base(file.GetName())
+	extension := path.Ext(filename)
+	return filename[0 : len(filename)-len(extension)]
+}
+
+func (g *openapiGenerator) fileExtension() string {
+	if g.yaml {
+		return ".yaml"
+	}
+	return ".json"
+}
+
 func (g *openapiGenerator) generateSingleFileOutput(filesToGen map[*protomodel.FileDescriptor]bool, response *pluginpb.CodeGeneratorResponse) {
 	messages := make(map[string]*protomodel.MessageDescriptor)
 	enums := make(map[string]*protomodel.EnumDescriptor)
@@ -310,10 +867,17 @@ func (g *openapiGenerator) generateFile(name string,
 	_ map[string]*protomodel.ServiceDescriptor,
 ) pluginpb.CodeGeneratorResponse_File {
 	g.messages = messages
+	g.enums = enums
+	g.pendingComponents = make(map[string]protomodel.CoreDesc)
+	g.messageSchemas = make(map[*protomodel.MessageDescriptor]*openapi3.Schema)
+	g.enumSchemas = make(map[*protomodel.EnumDescriptor]*openapi3.Schema)
 
 	allSchemas := make(map[string]*openapi3.SchemaRef)
 
 	for _, message := range messages {
+		if g.isExcluded(message) {
+			continue
+		}
 		// we generate the top-level messages here and the nested messages are generated
 		// inside each top-level message.
 		if message.Parent == nil {
@@ -322,12 +886,32 @@ func (g *openapiGenerator) generateFile(name string,
 	}
 
 	for _, enum := range enums {
+		if g.isExcluded(enum) {
+			continue
+		}
 		// when there is no parent to the enum.
 		if len(enum.QualifiedName()) == 1 {
 			g.generateEnum(enum, allSchemas)
 		}
 	}
 
+	// messages and enums referenced with `$ref` need a component of their own, even when they
+	// are nested or defined in another package.
+	for len(g.pendingComponents) > 0 {
+		for name, desc := range g.pendingComponents {
+			delete(g.pendingComponents, name)
+			if _, ok := allSchemas[name]; ok {
+				continue
+			}
+			switch d := desc.(type) {
+			case *protomodel.MessageDescriptor:
+				g.generateMessage(d, allSchemas)
+			case *protomodel.EnumDescriptor:
+				g.generateEnum(d, allSchemas)
+			}
+		}
+	}
+
 	var version string
 	var description string
 	// only get the API version when generate per package or per file,
@@ -367,20 +951,18 @@ func (g *openapiGenerator) generateFile(name string,
 	}
 
 	g.buffer.Reset()
-	var filename *string
+	filename := proto.String(name + g.fileExtension())
 	if g.yaml {
 		b, err := yaml.Marshal(o)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "unable to marshall the output of %v to yaml", name)
 		}
-		filename = proto.String(name + ".yaml")
 		g.buffer.Write(b)
 	} else {
 		b, err := json.MarshalIndent(o, "", "  ")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "unable to marshall the output of %v to json", name)
 		}
-		filename = proto.String(name + ".json")
 		g.buffer.Write(b)
 	}
 
@@ -391,6 +973,11 @@ func (g *openapiGenerator) generateFile(name string,
 }
 
 func (g *openapiGenerator) generateMessage(message *protomodel.MessageDescriptor, allSchemas map[string]*openapi3.SchemaRef) {
+	// the component of a message with a custom schema is the custom schema, like the fields holding it
+	if custom, ok := g.customSchema(message); ok {
+		allSchemas[g.absoluteName(message)] = g.generateSoloMessageSchema(message, &custom).NewRef()
+		return
+	}
 	if o := g.generateMessageSchema(message); o != nil {
 		allSchemas[g.absoluteName(message)] = o.NewRef()
 	}
@@ -403,6 +990,150 @@ func (g *openapiGenerator) generateSoloMessageSchema(message *protomodel.Message
 	return o
 }
 
+// newAnySchema returns the schema of an Any field restricted to the given message types, which is a
+// `oneOf` of the schemas of the messages extended with the `@type` property protojson adds. Well-known
+// types with a special JSON mapping are held by a `value` property instead. The `oneOf` has a
+// discriminator when every message is referenced, so that each `@type` is mapped to its component.
+func (g *openapiGenerator) newAnySchema(anyMsg *protomodel.MessageDescriptor, typeNames []string) *openapi3.Schema {
+	o := openapi3.NewObjectSchema()
+	o.Description = g.generateDescription(anyMsg)
+	mapping := make(openapi3.StringMap)
+
+	for _, name := range typeNames {
+		name = strings.TrimPrefix(name, ".")
+		// the names are checked by validateAnyTypes
+		msg := g.model.AllDescByName["."+name].(*protomodel.MessageDescriptor)
+		url := "type.googleapis.com/" + name
+
+		var variant *openapi3.Schema
+		if custom, ok := g.customSchema(msg); ok {
+			if strings.HasPrefix(name, "google.protobuf.") {
+				variant = openapi3.NewObjectSchema().WithProperty("value", &custom)
+			} else {
+				variant = &custom
+			}
+		} else if ref := g.typeRef(msg); ref != "" && !g.strict {
+			// referenced components are closed in strict mode, so they can't be extended with `@type`
+			variant = g.newRefSchema(ref, &openapi3.Types{openapi3.TypeObject})
+			mapping[url] = ref
+		} else if g.isRecursive(msg) {
+			variant = g.generateRecursiveMessageSchema(msg)
+			if g.strict && g.recursionConfiguration.Mode == RecursionRef {
+				// for the same reason, the message is truncated instead of referencing its component
+				_, _ = fmt.Fprintf(os.Stderr, "WARNING: recursive message %v held by an Any is truncated, "+
+					"as its component rejects the @type property in strict mode.\n", name)
+				variant = newPreserveUnknownFieldsSchema()
+			}
+		} else {
+			variant = g.generateMessageSchema(msg)
+		}
+
+		variant = withAnyType(variant, openapi3.NewStringSchema().WithEnum(url).NewRef())
+		variant.Title = name
+		o.OneOf = append(o.OneOf, variant.NewRef())
+	}
+
+	if len(mapping) == len(o.OneOf) {
+		o.Discriminator = &openapi3.Discriminator{PropertyName: "@type", Mapping: mapping}
+	}
+	return o
+}
+
+// withAnyType returns a copy of the schema of a message held by an Any, which requires the `@type`
+// property. The closed variants of a message rendered with `proto_oneof=variants` list the property too.
+func withAnyType(s *openapi3.Schema, typeSchema *openapi3.SchemaRef) *openapi3.Schema {
+	c := copySchema(s)
+	c.Properties = withProperty(c.Properties, "@type", typeSchema)
+	c.Required = append(slices.Clone(c.Required), "@type")
+
+	withVariants := func(refs openapi3.SchemaRefs) openapi3.SchemaRefs {
+		variants := make(openapi3.SchemaRefs, len(refs))
+		for i, ref := range refs {
+			variants[i] = ref
+			if ref.Ref == "" && ref.Value != nil && ref.Value.AdditionalProperties.Has != nil &&
+				!*ref.Value.AdditionalProperties.Has {
+				v := *ref.Value
+				v.Properties = withProperty(v.Properties, "@type", typeSchema)
+				variants[i] = v.NewRef()
+			}
+		}
+		return variants
+	}
+	if len(c.OneOf) > 0 {
+		c.OneOf = withVariants(c.OneOf)
+	}
+	if len(c.AllOf) > 0 {
+		allOf := make(openapi3.SchemaRefs, len(c.AllOf))
+		for i, ref := range c.AllOf {
+			allOf[i] = ref
+			if ref.Ref == "" && ref.Value != nil && len(ref.Value.OneOf) > 0 {
+				v := *ref.Value
+				v.OneOf = withVariants(v.OneOf)
+				allOf[i] = v.NewRef()
+			}
+		}
+		c.AllOf = allOf
+	}
+	return c
+}
+
+// withProperty returns a copy of the properties with the given one, since they may be shared with
+// other copies of the schema.
+func withProperty(properties openapi3.Schemas, name string, schema *openapi3.SchemaRef) openapi3.Schemas {
+	c := make(openapi3.Schemas, len(properties)+1)
+	for k, v := range properties {
+		c[k] = v
+	}
+	c[name] = schema
+	return c
+}
+
+// protoJSONWrapperSchema returns the schema of the 64-bit integer and floating point wrappers as encoded
+// by protojson, when protoJSONNumbers is set.
+func (g *openapiGenerator) protoJSONWrapperSchema(message *protomodel.MessageDescriptor) *openapi3.Schema {
+	if !g.protoJSONNumbers {
+		return nil
+	}
+
+	var schema *openapi3.Schema
+	switch g.absoluteName(message) {
+	case "google.protobuf.Int64Value":
+		schema = newProtoJSONInt64Schema(false)
+	case "google.protobuf.UInt64Value":
+		schema = newProtoJSONInt64Schema(true)
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue":
+		schema = newProtoJSONFloatSchema()
+	default:
+		return nil
+	}
+	schema.Nullable = true
+	return schema
+}
+
+// newProtoJSONInt64Schema returns the schema of a 64-bit integer, which protojson emits as a string
+// and accepts as either a string or a number.
+func newProtoJSONInt64Schema(unsigned bool) *openapi3.Schema {
+	if unsigned {
+		return openapi3.NewOneOfSchema(
+			openapi3.NewIntegerSchema().WithMin(0).WithFormat("uint64"),
+			openapi3.NewStringSchema().WithFormat("int64").WithPattern(`^[0-9]+$`),
+		)
+	}
+	return openapi3.NewOneOfSchema(
+		openapi3.NewInt64Schema(),
+		openapi3.NewStringSchema().WithFormat("int64").WithPattern(`^-?[0-9]+$`),
+	)
+}
+
+// newProtoJSONFloatSchema returns the schema of a floating point number, whose special values protojson
+// emits as strings.
+func newProtoJSONFloatSchema() *openapi3.Schema {
+	return openapi3.NewOneOfSchema(
+		openapi3.NewFloat64Schema(),
+		openapi3.NewStringSchema().WithEnum("NaN", "Infinity", "-Infinity"),
+	)
+}
+
 func (g *openapiGenerator) generateSoloInt64Schema() *openapi3.Schema {
 	schema := openapi3.NewInt64Schema()
 	schema.Extensions = map[string]interface{}{
@@ -412,60 +1143,155 @@ func (g *openapiGenerator) generateSoloInt64Schema() *openapi3.Schema {
 	return schema
 }
 
+// generateMessageSchema returns the schema of the message, which is built once per output document.
+// Callers own the returned schema and may set its top-level properties.
 func (g *openapiGenerator) generateMessageSchema(message *protomodel.MessageDescriptor) *openapi3.Schema {
 	// skip MapEntry message because we handle map using the map's repeated field.
 	if message.GetOptions().GetMapEntry() {
 		return nil
 	}
+	if o, ok := g.messageSchemas[message]; ok {
+		return copySchema(o)
+	}
+
+	cuts := g.recursionCuts
+	o := g.buildMessageSchema(message)
+	// a schema truncated by a cycle depends on the path it was generated from, so it can't be reused
+	if g.recursionCuts != cuts {
+		return o
+	}
+	g.messageSchemas[message] = o
+	return copySchema(o)
+}
+
+func (g *openapiGenerator) buildMessageSchema(message *protomodel.MessageDescriptor) *openapi3.Schema {
+	g.messageStack = append(g.messageStack, message)
+	defer func() {
+		g.messageStack = g.messageStack[:len(g.messageStack)-1]
+	}()
+
 	o := openapi3.NewObjectSchema()
 	o.Description = g.generateDescription(message)
-	msgRules := g.validationRules(message)
-	g.mustApplyRulesToSchema(msgRules, o, markers.TargetType)
+	g.mustApplyMarkersToSchema(message, o)
+	g.applyStabilityExtension(message, o)
+	g.applyDeprecation(message, o)
+	resource := message.Resource()
+	if resource != nil {
+		setExtension(o, "x-resource-type", resource.Type)
+	}
 
-	oneOfFields := make(map[int32][]string)
+	// the names of the members of each oneof
+	oneOfFields := make(map[*protomodel.OneofDescriptor][][]string)
 	var requiredFields []string
 	for _, field := range message.Fields {
+		if g.isExcluded(field) {
+			continue
+		}
+
 		repeated := field.IsRepeated()
-		fieldName := g.fieldName(field)
+		fieldNames := g.fieldNames(field)
 		fieldDesc := g.generateDescription(field)
-		fieldRules := g.validationRules(field)
+		fieldMarkers := g.parseComments(field).markers
 
-		// If the field is a oneof, we need to add the oneof property to the schema
-		if field.OneofIndex != nil {
-			idx := *field.OneofIndex
-			oneOfFields[idx] = append(oneOfFields[idx], fieldName)
+		// If the field is a oneof, we need to add the oneof property to the schema.
+		// proto3 optional fields are wrapped in a synthetic oneof which is not a real oneof.
+		inOneof := field.Oneof != nil && !field.Oneof.IsSynthetic()
+		if inOneof {
+			oneOfFields[field.Oneof] = append(oneOfFields[field.Oneof], fieldNames)
 		}
 
-		if g.markerRegistry.IsRequired(fieldRules) {
-			requiredFields = append(requiredFields, fieldName)
+		required := fieldMarkers.IsRequired() || field.IsRequired() ||
+			field.HasFieldBehavior(protomodel.FieldBehaviorRequired)
+		if len(fieldNames) > 1 {
+			// only one of the names of the field may be set, which the oneof schema already ensures
+			// for its members
+			if required || !inOneof || g.protoOneof.Mode == ProtoOneofNone {
+				o.AllOf = append(o.AllOf, newFieldNamesSchema(fieldNames, required).NewRef())
+			}
+		} else if required {
+			requiredFields = append(requiredFields, fieldNames[0])
 		}
 
-		schemaType := g.markerRegistry.GetSchemaType(fieldRules, markers.TargetField)
+		schemaType := fieldMarkers.SchemaType()
 		if schemaType != "" {
 			tmp := getSoloSchemaForMarkerType(schemaType)
 			schema := getSchemaIfRepeated(&tmp, repeated)
 			schema.Description = fieldDesc
-			g.mustApplyRulesToSchema(fieldRules, schema, markers.TargetField)
-			o.WithProperty(fieldName, schema)
+			g.applyPresence(field, schema)
+			g.mustApplyMarkersToSchema(field, schema)
+			g.applyFieldBehavior(field, schema)
+			g.applyStabilityExtension(field, schema)
+			g.applyDeprecation(field, schema)
+			g.applySchemaOverride(field, schema)
+			for _, fieldName := range fieldNames {
+				o.WithProperty(fieldName, schema)
+			}
 			continue
 		}
 
 		sr := g.fieldTypeRef(field)
-		g.mustApplyRulesToSchema(fieldRules, sr.Value, markers.TargetField)
-		o.WithProperty(fieldName, sr.Value)
+		g.applyPresence(field, sr.Value)
+		g.applyDefaultValue(field, sr.Value)
+		g.applyResourceName(resource, field, sr.Value)
+		g.mustApplyMarkersToSchema(field, sr.Value)
+		g.applyFieldBehavior(field, sr.Value)
+		g.applyStabilityExtension(field, sr.Value)
+		g.applyDeprecation(field, sr.Value)
+		g.applySchemaOverride(field, sr.Value)
+		for _, fieldName := range fieldNames {
+			o.WithProperty(fieldName, sr.Value)
+		}
 	}
 
 	if len(requiredFields) > 0 {
 		o.Required = requiredFields
 	}
 
-	if g.protoOneof {
-		// Add protobuf oneof schema for this message
-		oneOfs := make([][]*openapi3.Schema, len(oneOfFields))
-		for idx := range oneOfFields {
+	// the schemas listing the properties of the message, which are closed in strict mode
+	closable := []*openapi3.Schema{o}
+
+	if g.protoOneof.Mode == ProtoOneofNone {
+		// required oneofs still need one of their members
+		for _, oneof := range message.Oneofs {
+			if members, ok := oneOfFields[oneof]; ok && g.isOneofRequired(oneof) {
+				o.AllOf = append(o.AllOf, openapi3.NewAnyOfSchema(newRequiredSchemas(members)...).NewRef())
+			}
+		}
+	} else {
+		// Add protobuf oneof schema for this message
+		// oneofs whose fields are all excluded from the output are skipped
+		oneOfs := make([][]*openapi3.Schema, 0, len(oneOfFields))
+		for _, oneof := range message.Oneofs {
+			members, ok := oneOfFields[oneof]
+			if !ok {
+				continue
+			}
+			required := g.isOneofRequired(oneof)
+			if g.protoOneof.Mode == ProtoOneofVariants {
+				oneOfs = append(oneOfs, g.newProtoOneofVariants(o, oneof, members, required))
+				continue
+			}
+			var fields []string
+			for _, names := range members {
+				fields = append(fields, names...)
+			}
 			// oneOfSchemas is a collection (not and required schemas) that should be assigned to the schemas's oneOf field
-			oneOfSchemas := newProtoOneOfSchema(oneOfFields[idx]...)
-			oneOfs[idx] = append(oneOfs[idx], oneOfSchemas...)
+			oneOfSchemas := newProtoOneOfSchema(fields...)
+			if required {
+				// drop the schema accepting none of the fields
+				oneOfSchemas = oneOfSchemas[1:]
+			}
+			oneOfs = append(oneOfs, oneOfSchemas)
+		}
+
+		if g.protoOneof.Mode == ProtoOneofVariants && len(oneOfs) > 0 {
+			// the properties of the message are listed by its variants
+			o.Properties = nil
+			o.Required = nil
+			closable = nil
+			for _, variants := range oneOfs {
+				closable = append(closable, variants...)
+			}
 		}
 
 		switch len(oneOfs) {
@@ -482,9 +1308,315 @@ func (g *openapiGenerator) generateMessageSchema(message *protomodel.MessageDesc
 		}
 	}
 
+	// messages preserving unknown fields stay open
+	if g.strict && o.Extensions["x-kubernetes-preserve-unknown-fields"] != true {
+		for _, s := range closable {
+			s.AdditionalProperties = openapi3.AdditionalProperties{Has: openapi3.BoolPtr(false)}
+		}
+	}
+
 	return o
 }
 
+// applyMapKeyValidation restricts the keys of a map to the JSON form of its key type, since JSON
+// object keys are always strings. String keys are left as is.
+func applyMapKeyValidation(key *protomodel.FieldDescriptor, o *openapi3.Schema) {
+	var rule markers.XValidation
+	switch key.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32, descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		rule = markers.XValidation{Rule: `self.all(k, k.matches(r'^-?(0|[1-9][0-9]*)$'))`, Message: "map keys must be integers"}
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT64, descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
+		rule = markers.XValidation{Rule: `self.all(k, k.matches(r'^(0|[1-9][0-9]*)$'))`, Message: "map keys must be unsigned integers"}
+	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
+		rule = markers.XValidation{Rule: `self.all(k, k == 'true' || k == 'false')`, Message: "map keys must be true or false"}
+	default:
+		return
+	}
+	rule.ApplyToSchema(o)
+}
+
+// applyPresence marks proto3 optional fields and scalar fields with explicit presence as nullable,
+// since protojson accepts `null` for them to mean the field is unset. Other message fields and oneof
+// members always have presence, so they are left as is.
+func (g *openapiGenerator) applyPresence(field *protomodel.FieldDescriptor, o *openapi3.Schema) {
+	if field.IsProto3Optional() {
+		o.Nullable = true
+		return
+	}
+	if field.IsMessage() || field.OneofIndex != nil {
+		return
+	}
+	if field.HasPresence() && !field.IsRequired() {
+		o.Nullable = true
+	}
+}
+
+// applyDefaultValue sets the default of the schema from the field's `default_value`, which is only
+// available for fields with explicit presence such as proto2 optional fields.
+func (g *openapiGenerator) applyDefaultValue(field *protomodel.FieldDescriptor, o *openapi3.Schema) {
+	if v, ok := field.JSONDefaultValue(); ok {
+		o.Default = v
+	}
+}
+
+// applyFieldBehavior translates the `google.api.field_behavior` option of a field to its schema.
+// Fields set by the server are read only, fields only set by the client are write only, and
+// immutable fields get a transition rule that rejects any change to their value.
+// REQUIRED fields are handled by the message schema.
+func (g *openapiGenerator) applyFieldBehavior(field *protomodel.FieldDescriptor, o *openapi3.Schema) {
+	for _, behavior := range field.FieldBehaviors() {
+		switch behavior {
+		case protomodel.FieldBehaviorOutputOnly:
+			o.ReadOnly = true
+		case protomodel.FieldBehaviorInputOnly:
+			o.WriteOnly = true
+		case protomodel.FieldBehaviorImmutable:
+			// the rule is a Kubernetes validation, which is omitted like the markers
+			if g.disableKubeMarkers {
+				continue
+			}
+			markers.XValidation{Rule: "self == oldSelf", Message: "Value is immutable"}.ApplyToSchema(o)
+		}
+	}
+}
+
+// applyResourceName restricts the name field of a resource, and the fields referencing a resource, to
+// the name patterns of the resource. Referencing fields are also tagged with the referenced type.
+func (g *openapiGenerator) applyResourceName(resource *protomodel.ResourceDescriptor, field *protomodel.FieldDescriptor,
+	o *openapi3.Schema) {
+	if field.GetType() != descriptorpb.FieldDescriptorProto_TYPE_STRING {
+		return
+	}
+	s := o
+	if field.IsRepeated() {
+		s = o.Items.Value
+	}
+
+	if resource != nil && field.GetName() == resource.NameField {
+		s.Pattern = resourceNamePattern(resource.Patterns)
+		return
+	}
+
+	ref := field.ResourceReference()
+	switch {
+	case ref == nil:
+		return
+	case ref.Type != "":
+		setExtension(s, "x-resource-reference", ref.Type)
+		if referenced, ok := g.model.AllResourcesByType[ref.Type]; ok {
+			s.Pattern = resourceNamePattern(referenced.Patterns)
+		}
+	case ref.ChildType != "":
+		setExtension(s, "x-resource-child-type", ref.ChildType)
+	}
+}
+
+// resourceNamePattern returns a regular expression matching the resource names of any of the given
+// patterns, in which each variable matches a single segment of the name. An empty expression is
+// returned when any name is accepted.
+func resourceNamePattern(patterns []string) string {
+	alternatives := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "" || pattern == "*" {
+			return ""
+		}
+		var sb strings.Builder
+		for pattern != "" {
+			loc := resourceVariableRegexp.FindStringIndex(pattern)
+			if loc == nil {
+				sb.WriteString(regexp.QuoteMeta(pattern))
+				break
+			}
+			sb.WriteString(regexp.QuoteMeta(pattern[:loc[0]]))
+			sb.WriteString("[^/]+")
+			pattern = pattern[loc[1]:]
+		}
+		alternatives = append(alternatives, sb.String())
+	}
+
+	switch len(alternatives) {
+	case 0:
+		return ""
+	case 1:
+		return "^" + alternatives[0] + "$"
+	default:
+		return "^(?:" + strings.Join(alternatives, "|") + ")$"
+	}
+}
+
+var resourceVariableRegexp = regexp.MustCompile(`\{[^}]*\}`)
+
+// applySchemaOverride merges the user-supplied schema fragment of a field, if any, into its schema.
+func (g *openapiGenerator) applySchemaOverride(field *protomodel.FieldDescriptor, o *openapi3.Schema) {
+	// patterns only match messages, so fields are matched by their exact name
+	name := g.absoluteName(field)
+	patch, ok := g.schemaOverrides[name]
+	if !ok {
+		return
+	}
+	g.matchedNames[name] = true
+
+	b, err := json.Marshal(o)
+	if err != nil {
+		log.Panicf("unable to marshal the schema of %s: %v", g.absoluteName(field), err)
+	}
+	var target map[string]interface{}
+	if err := json.Unmarshal(b, &target); err != nil {
+		log.Panicf("unable to unmarshal the schema of %s: %v", g.absoluteName(field), err)
+	}
+	mergePatch(target, patch)
+
+	b, err = json.Marshal(target)
+	if err != nil {
+		log.Panicf("unable to marshal the overridden schema of %s: %v", g.absoluteName(field), err)
+	}
+	patched := openapi3.NewSchema()
+	if err := json.Unmarshal(b, patched); err != nil {
+		log.Panicf("invalid overridden schema of %s: %v", g.absoluteName(field), err)
+	}
+	*o = *patched
+}
+
+// customSchema returns the pre-defined schema of a message, if any.
+func (g *openapiGenerator) customSchema(message *protomodel.MessageDescriptor) (openapi3.Schema, bool) {
+	key, ok := g.matchName(g.absoluteName(message), func(name string) bool {
+		_, ok := g.customSchemasByMessageName[name]
+		return ok
+	}, g.customSchemaPatterns)
+	if !ok {
+		return openapi3.Schema{}, false
+	}
+	return g.customSchemasByMessageName[key], true
+}
+
+// matchName returns the key matching a fully qualified name, which is either the name itself or the
+// longest pattern matching the name, and records that the key was used.
+func (g *openapiGenerator) matchName(name string, has func(string) bool, patterns []*namePattern) (string, bool) {
+	key := ""
+	if has(name) {
+		key = name
+	} else {
+		for i := len(patterns) - 1; i >= 0; i-- {
+			if patterns[i].re.MatchString(name) {
+				key = patterns[i].pattern
+				break
+			}
+		}
+	}
+	if key == "" {
+		return "", false
+	}
+	g.matchedNames[key] = true
+	return key, true
+}
+
+// reportUnmatchedNames warns about the user-supplied names and patterns which matched no message or field.
+func (g *openapiGenerator) reportUnmatchedNames() {
+	var unmatched []string
+	for name := range g.userNames {
+		if !g.matchedNames[name] {
+			unmatched = append(unmatched, name)
+		}
+	}
+	sort.Strings(unmatched)
+	for _, name := range unmatched {
+		_, _ = fmt.Fprintf(os.Stderr, "WARNING: %v of %v matched no message or field.\n", name, g.userNames[name])
+	}
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386) to a JSON object.
+func mergePatch(target map[string]interface{}, patch map[string]interface{}) {
+	for k, v := range patch {
+		if v == nil {
+			delete(target, k)
+			continue
+		}
+		if p, ok := v.(map[string]interface{}); ok {
+			if t, ok := target[k].(map[string]interface{}); ok {
+				mergePatch(t, p)
+				continue
+			}
+		}
+		target[k] = v
+	}
+}
+
+// isRecursive returns true if generating the message again would recurse past the configured depth.
+func (g *openapiGenerator) isRecursive(message *protomodel.MessageDescriptor) bool {
+	count := 0
+	for _, m := range g.messageStack {
+		if m == message {
+			count++
+		}
+	}
+	if g.recursionConfiguration.Mode == RecursionRef {
+		return count > 0
+	}
+	return count > g.recursionConfiguration.Depth
+}
+
+// generateRecursiveMessageSchema breaks a cycle in the message graph, either by referencing the component
+// of the message or by replacing it with an object which preserves unknown fields.
+func (g *openapiGenerator) generateRecursiveMessageSchema(message *protomodel.MessageDescriptor) *openapi3.Schema {
+	g.reportCycle(message)
+	g.recursionCuts++
+
+	if g.recursionConfiguration.Mode == RecursionRef {
+		return g.newRefSchema(g.componentRef(message), &openapi3.Types{openapi3.TypeObject})
+	}
+
+	return newPreserveUnknownFieldsSchema()
+}
+
+// reportCycle warns about a cycle in the message graph, once per cycle.
+func (g *openapiGenerator) reportCycle(message *protomodel.MessageDescriptor) {
+	start := 0
+	for i, m := range g.messageStack {
+		if m == message {
+			start = i
+		}
+	}
+	var names []string
+	for _, m := range g.messageStack[start:] {
+		names = append(names, g.absoluteName(m))
+	}
+	names = append(names, g.absoluteName(message))
+	cycle := strings.Join(names, " -> ")
+
+	if g.reportedCycles[cycle] {
+		return
+	}
+	g.reportedCycles[cycle] = true
+	_, _ = fmt.Fprintf(os.Stderr, "WARNING: recursive message cycle %v is rendered using %v.\n",
+		cycle, g.recursionConfiguration.Mode)
+}
+
+// copySchema returns a copy of a schema whose top-level properties and extensions can be modified
+// without affecting the original. Nested schemas are shared.
+func copySchema(o *openapi3.Schema) *openapi3.Schema {
+	c := *o
+	if o.Extensions != nil {
+		c.Extensions = make(map[string]interface{}, len(o.Extensions))
+		for k, v := range o.Extensions {
+			c.Extensions[k] = v
+		}
+	}
+	return &c
+}
+
+func newPreserveUnknownFieldsSchema() *openapi3.Schema {
+	return &openapi3.Schema{
+		Type:       &openapi3.Types{openapi3.TypeObject},
+		Properties: make(map[string]*openapi3.SchemaRef),
+		Extensions: map[string]interface{}{
+			"x-kubernetes-preserve-unknown-fields": true,
+		},
+	}
+}
+
 func getSoloSchemaForMarkerType(t markers.Type) openapi3.Schema {
 	switch t {
 	case markers.TypeObject:
@@ -512,6 +1644,112 @@ func getSchemaIfRepeated(schema *openapi3.Schema, repeated bool) *openapi3.Schem
 	return schema
 }
 
+// newFieldNamesSchema returns a schema which forbids setting a field under several names at once, and
+// requires one of them when the field is required.
+func newFieldNamesSchema(names []string, required bool) *openapi3.Schema {
+	if required {
+		schemas := make([]*openapi3.Schema, len(names))
+		for i, name := range names {
+			schemas[i] = openapi3.NewSchema()
+			schemas[i].Required = []string{name}
+		}
+		return openapi3.NewOneOfSchema(schemas...)
+	}
+
+	allNames := openapi3.NewSchema()
+	allNames.Required = names
+	schema := openapi3.NewSchema()
+	schema.Not = allNames.NewRef()
+	return schema
+}
+
+// newProtoOneofVariants returns an object schema per member of a oneof, holding the properties of the
+// message except the other members of the oneof and requiring the member, and a schema for when no
+// member is set unless the oneof is required. Variants are described by the comments of the oneof.
+func (g *openapiGenerator) newProtoOneofVariants(message *openapi3.Schema, oneof *protomodel.OneofDescriptor,
+	members [][]string, required bool) []*openapi3.Schema {
+	description := g.generateDescription(oneof)
+	newVariant := func() *openapi3.Schema {
+		variant := openapi3.NewObjectSchema()
+		variant.Description = description
+		variant.Required = message.Required
+		if g.protoOneof.NameExtension {
+			variant.Extensions = map[string]interface{}{"x-oneof-name": oneof.GetName()}
+		}
+		return variant
+	}
+	isMember := make(map[string]bool)
+	for _, names := range members {
+		for _, name := range names {
+			isMember[name] = true
+		}
+	}
+
+	variants := make([]*openapi3.Schema, 0, len(members)+1)
+	unset := newVariant()
+	unset.Title = "none"
+	for name, prop := range message.Properties {
+		if !isMember[name] {
+			unset.WithPropertyRef(name, prop)
+		}
+	}
+	for _, names := range members {
+		variant := newVariant()
+		variant.Title = names[0]
+		for name, prop := range message.Properties {
+			if !isMember[name] || slices.Contains(names, name) {
+				variant.WithPropertyRef(name, prop)
+			}
+		}
+		if len(names) > 1 {
+			variant.AllOf = openapi3.SchemaRefs{newFieldNamesSchema(names, true).NewRef()}
+		} else {
+			variant.Required = append(slices.Clone(variant.Required), names[0])
+		}
+		variants = append(variants, variant)
+	}
+	if required {
+		return variants
+	}
+	unset.Not = openapi3.NewAnyOfSchema(newRequiredSchemas(members)...).NewRef()
+	return append(variants, unset)
+}
+
+// newRequiredSchemas returns a schema requiring each name of the members of a oneof.
+func newRequiredSchemas(members [][]string) []*openapi3.Schema {
+	var schemas []*openapi3.Schema
+	for _, names := range members {
+		for _, name := range names {
+			s := openapi3.NewSchema()
+			s.Required = []string{name}
+			schemas = append(schemas, s)
+		}
+	}
+	return schemas
+}
+
+// isOneofRequired returns true if one of the members of the oneof must be set, as requested by the
+// Required marker, the only marker accepted on oneofs.
+func (g *openapiGenerator) isOneofRequired(oneof *protomodel.OneofDescriptor) bool {
+	return g.parseComments(oneof).markers.IsRequired()
+}
+
+// parseOneofMarkers keeps the Required markers of a oneof. Comments of oneofs were not parsed for
+// markers before, so the other markers are ignored with a warning rather than failing the generation.
+func (g *openapiGenerator) parseOneofMarkers(oneof *protomodel.OneofDescriptor, rules []string) markers.Markers {
+	var required markers.Markers
+	for _, rule := range rules {
+		parsed, err := g.markerRegistry.Parse([]string{rule}, markers.TargetField)
+		if err == nil && parsed.IsRequired() {
+			required = append(required, parsed...)
+			continue
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "WARNING: marker %v of oneof %v is ignored, only the Required marker applies to oneofs.\n",
+			rule, g.absoluteName(oneof))
+	}
+	return required
+}
+
 // newProtoOneOfSchema returns a schema that can be used to represent a collection of fields
 // that must be encoded as a oneOf in OpenAPI.
 // For e.g., if the fields x and y are a part of a proto oneof, then they can be represented as
@@ -568,7 +1806,18 @@ func (g *openapiGenerator) generateEnum(enum *protomodel.EnumDescriptor, allSche
 	allSchemas[g.absoluteName(enum)] = o.NewRef()
 }
 
+// generateEnumSchema returns the schema of the enum, which is built once per output document.
+// Callers own the returned schema and may set its top-level properties.
 func (g *openapiGenerator) generateEnumSchema(enum *protomodel.EnumDescriptor) *openapi3.Schema {
+	o, ok := g.enumSchemas[enum]
+	if !ok {
+		o = g.buildEnumSchema(enum)
+		g.enumSchemas[enum] = o
+	}
+	return copySchema(o)
+}
+
+func (g *openapiGenerator) buildEnumSchema(enum *protomodel.EnumDescriptor) *openapi3.Schema {
 	/**
 	  The out of the box solution created an enum like:
 	  	enum:
@@ -584,18 +1833,44 @@ func (g *openapiGenerator) generateEnumSchema(enum *protomodel.EnumDescriptor) *
 	*/
 	o := openapi3.NewStringSchema()
 	o.Description = g.generateDescription(enum)
+	g.applyStabilityExtension(enum, o)
+	if g.enumIntValues {
+		g.applyEnumValueExtensions(enum, o)
+	}
+	if g.enumConfiguration.Deprecated == DeprecatedEnumValueAnnotate {
+		g.applyDeprecatedEnumValues(enum, o)
+	}
+	g.applyDeprecation(enum, o)
 
 	// If the schema should be int or string, mark it as such
 	if g.enumAsIntOrString {
-		o.Extensions = map[string]interface{}{
-			"x-kubernetes-int-or-string": true,
+		if o.Extensions == nil {
+			o.Extensions = map[string]interface{}{}
+		}
+		o.Extensions["x-kubernetes-int-or-string"] = true
+
+		// closed enums only accept their declared values, whether given as names or numbers, and so do
+		// enums listing the numbers of their values
+		if enum.IsClosed() || g.enumIntValues {
+			names := openapi3.NewStringSchema()
+			numbers := openapi3.NewInt32Schema()
+			seen := make(map[int32]bool)
+			for _, v := range g.enumValues(enum) {
+				names.Enum = append(names.Enum, v.GetName())
+				// aliases share the number of another value
+				if !seen[v.GetNumber()] {
+					seen[v.GetNumber()] = true
+					numbers.Enum = append(numbers.Enum, v.GetNumber())
+				}
+			}
+			o.Type = nil
+			o.AnyOf = openapi3.SchemaRefs{numbers.NewRef(), names.NewRef()}
 		}
 		return o
 	}
 
 	// otherwise, return define the expected string values
-	values := enum.GetValue()
-	for _, v := range values {
+	for _, v := range g.enumValues(enum) {
 		o.Enum = append(o.Enum, v.GetName())
 	}
 	o.Type = &openapi3.Types{openapi3.TypeString}
@@ -603,6 +1878,159 @@ func (g *openapiGenerator) generateEnumSchema(enum *protomodel.EnumDescriptor) *
 	return o
 }
 
+// applyEnumValueExtensions lists the name and description of each value of the enum in the
+// `x-enum-varnames` and `x-enum-descriptions` extensions.
+func (g *openapiGenerator) applyEnumValueExtensions(enum *protomodel.EnumDescriptor, o *openapi3.Schema) {
+	var names []interface{}
+	var descriptions []interface{}
+	described := false
+	for _, v := range g.enumValues(enum) {
+		names = append(names, v.GetName())
+		desc := g.generateDescription(v)
+		descriptions = append(descriptions, desc)
+		described = described || desc != ""
+	}
+
+	extensions := make(map[string]interface{}, len(o.Extensions)+2)
+	for k, v := range o.Extensions {
+		extensions[k] = v
+	}
+	extensions["x-enum-varnames"] = names
+	if described {
+		extensions["x-enum-descriptions"] = descriptions
+	}
+	o.Extensions = extensions
+}
+
+// enumValues returns the values of the enum that are not excluded from the output.
+func (g *openapiGenerator) enumValues(enum *protomodel.EnumDescriptor) []*protomodel.EnumValueDescriptor {
+	var values []*protomodel.EnumValueDescriptor
+	numbers := make(map[int32]bool)
+	for _, v := range enum.Values {
+		if g.isExcluded(v) {
+			continue
+		}
+		if g.enumConfiguration.ExcludeUnspecified && v.IsUnspecified() {
+			continue
+		}
+		if g.enumConfiguration.Deprecated == DeprecatedEnumValueDrop && v.IsDeprecated() {
+			continue
+		}
+		numbers[v.GetNumber()] = true
+		values = append(values, v)
+	}
+	if g.enumConfiguration.Aliases != EnumAliasCanonical {
+		return values
+	}
+
+	// protojson emits the name declared first for a number, which is listed in place of the remaining
+	// names of the number even when it was omitted itself
+	var canonical []*protomodel.EnumValueDescriptor
+	for _, v := range enum.Values {
+		if numbers[v.GetNumber()] {
+			delete(numbers, v.GetNumber())
+			canonical = append(canonical, v)
+		}
+	}
+	return canonical
+}
+
+// applyDeprecatedEnumValues lists the deprecated values of the enum in the `x-enum-deprecated` extension.
+func (g *openapiGenerator) applyDeprecatedEnumValues(enum *protomodel.EnumDescriptor, o *openapi3.Schema) {
+	var deprecated []interface{}
+	for _, v := range g.enumValues(enum) {
+		if v.IsDeprecated() {
+			deprecated = append(deprecated, v.GetName())
+		}
+	}
+	if len(deprecated) == 0 {
+		return
+	}
+
+	setExtension(o, "x-enum-deprecated", deprecated)
+}
+
+// isExcluded returns true if the descriptor should be omitted from the output, either because
+// it is hidden or because its `$class:` annotation is filtered out.
+func (g *openapiGenerator) isExcluded(desc protomodel.CoreDesc) bool {
+	if g.excludeHidden && desc.IsHidden() {
+		return true
+	}
+
+	cl := desc.Class()
+	if cl == "" {
+		// descriptors without a class are never filtered
+		return false
+	}
+	if g.classFilter.excluded[cl] {
+		return true
+	}
+	return len(g.classFilter.included) > 0 && !g.classFilter.included[cl]
+}
+
+// applyStabilityExtension tags the schema with the `$class:` annotation of the descriptor.
+func (g *openapiGenerator) applyStabilityExtension(desc protomodel.CoreDesc, o *openapi3.Schema) {
+	if !g.classFilter.stabilityExtension || desc.Class() == "" {
+		return
+	}
+	setExtension(o, "x-stability", desc.Class())
+}
+
+// setExtension sets an extension of the schema, copying the extensions as they may be shared with
+// one of the predefined schemas.
+func setExtension(o *openapi3.Schema, name string, value interface{}) {
+	extensions := make(map[string]interface{}, len(o.Extensions)+1)
+	for k, v := range o.Extensions {
+		extensions[k] = v
+	}
+	extensions[name] = value
+	o.Extensions = extensions
+}
+
+// applyDeprecation marks the schema of a deprecated field, message or enum as configured.
+func (g *openapiGenerator) applyDeprecation(desc protomodel.CoreDesc, o *openapi3.Schema) {
+	if g.deprecationConfiguration.Mode == DeprecationNone || !isDeprecated(desc) {
+		return
+	}
+
+	if g.deprecationConfiguration.Mode == DeprecationMark {
+		o.Deprecated = true
+	}
+	if g.descriptionConfiguration.IncludeDescriptionInSchema {
+		if o.Description == "" {
+			o.Description = "Deprecated."
+		} else {
+			o.Description = "Deprecated: " + o.Description
+		}
+	}
+
+	if g.deprecationConfiguration.Warning {
+		kind := "message"
+		switch desc.(type) {
+		case *protomodel.FieldDescriptor:
+			kind = "field"
+		case *protomodel.EnumDescriptor:
+			kind = "enum"
+		}
+		setExtension(o, "x-deprecation-warning", fmt.Sprintf("%s %s is deprecated", kind, g.absoluteName(desc)))
+	}
+}
+
+// isDeprecated returns true if the descriptor is marked with the `deprecated` option.
+func isDeprecated(desc protomodel.CoreDesc) bool {
+	switch d := desc.(type) {
+	case *protomodel.MessageDescriptor:
+		return d.GetOptions().GetDeprecated()
+	case *protomodel.FieldDescriptor:
+		return d.GetOptions().GetDeprecated()
+	case *protomodel.EnumDescriptor:
+		return d.GetOptions().GetDeprecated()
+	case *protomodel.EnumValueDescriptor:
+		return d.IsDeprecated()
+	}
+	return false
+}
+
 func (g *openapiGenerator) absoluteName(desc protomodel.CoreDesc) string {
 	typeName := protomodel.DottedName(desc)
 	return desc.PackageDesc().Name + "." + typeName
@@ -633,37 +2061,28 @@ func (g *openapiGenerator) generateMultiLineDescription(desc protomodel.CoreDesc
 	if !g.descriptionConfiguration.IncludeDescriptionInSchema {
 		return ""
 	}
-	comments, _ := g.parseComments(desc)
-	return comments
+	return g.parseComments(desc).description
 }
 
-func (g *openapiGenerator) mustApplyRulesToSchema(
-	rules []string,
-	o *openapi3.Schema,
-	target kubemarkers.TargetType,
-) {
+func (g *openapiGenerator) mustApplyMarkersToSchema(desc protomodel.CoreDesc, o *openapi3.Schema) {
 	if g.disableKubeMarkers {
 		return
 	}
-	g.markerRegistry.MustApplyRulesToSchema(rules, o, target)
+	g.parseComments(desc).markers.MustApplyToSchema(o)
 }
 
-func (g *openapiGenerator) validationRules(desc protomodel.CoreDesc) []string {
-	_, validationRules := g.parseComments(desc)
-	return validationRules
-}
+// parseComments returns the description and markers of a descriptor, which are parsed the first
+// time the descriptor is seen. Markers are only parsed for messages, fields and oneofs, the only
+// descriptors they apply to.
+func (g *openapiGenerator) parseComments(desc protomodel.CoreDesc) *descComments {
+	if parsed, ok := g.comments[desc]; ok {
+		return parsed
+	}
 
-func (g *openapiGenerator) parseComments(desc protomodel.CoreDesc) (comments string, validationRules []string) {
 	c := strings.TrimSpace(desc.Location().GetLeadingComments())
 	blocks := strings.Split(c, "\n\n")
 
-	var ignoredKubeMarkersRegexp *regexp.Regexp
-	if len(g.ignoredKubeMarkerSubstrings) > 0 {
-		ignoredKubeMarkersRegexp = regexp.MustCompile(
-			fmt.Sprintf("(?:%s)", strings.Join(g.ignoredKubeMarkerSubstrings, "|")),
-		)
-	}
-
+	var validationRules []string
 	var sb strings.Builder
 	for i, block := range blocks {
 		if shouldNotRenderDesc(strings.TrimSpace(block)) {
@@ -684,7 +2103,7 @@ func (g *openapiGenerator) parseComments(desc protomodel.CoreDesc) (comments str
 			}
 
 			if strings.HasPrefix(l, markers.Kubebuilder) {
-				if isIgnoredKubeMarker(ignoredKubeMarkersRegexp, l) {
+				if isIgnoredKubeMarker(g.ignoredKubeMarkersRegexp, l) {
 					continue
 				}
 
@@ -701,8 +2120,18 @@ func (g *openapiGenerator) parseComments(desc protomodel.CoreDesc) (comments str
 		sb.WriteString(block)
 	}
 
-	comments = strings.TrimSpace(sb.String())
-	return
+	parsed := &descComments{description: strings.TrimSpace(sb.String())}
+	switch d := desc.(type) {
+	case *protomodel.MessageDescriptor:
+		parsed.markers = g.markerRegistry.MustParse(validationRules, markers.TargetType)
+	case *protomodel.FieldDescriptor:
+		validationRules = append(validationRules, commonTypeFieldRules[g.absoluteName(desc)]...)
+		parsed.markers = g.markerRegistry.MustParse(validationRules, markers.TargetField)
+	case *protomodel.OneofDescriptor:
+		parsed.markers = g.parseOneofMarkers(d, validationRules)
+	}
+	g.comments[desc] = parsed
+	return parsed
 }
 
 func shouldNotRenderDesc(desc string) bool {
@@ -720,14 +2149,20 @@ func (g *openapiGenerator) fieldType(field *protomodel.FieldDescriptor) *openapi
 	var isMap bool
 	switch *field.Type {
 	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
-		schema = openapi3.NewFloat64Schema()
+		if g.protoJSONNumbers {
+			schema = newProtoJSONFloatSchema()
+		} else {
+			schema = openapi3.NewFloat64Schema()
+		}
 
 	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_SINT32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
 		schema = openapi3.NewInt32Schema()
 
 	case descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_SINT64,
 		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64, descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
-		if g.intNative {
+		if g.protoJSONNumbers {
+			schema = newProtoJSONInt64Schema(*field.Type == descriptorpb.FieldDescriptorProto_TYPE_FIXED64)
+		} else if g.intNative {
 			schema = openapi3.NewInt64Schema()
 		} else {
 			schema = g.generateSoloInt64Schema()
@@ -740,7 +2175,9 @@ func (g *openapiGenerator) fieldType(field *protomodel.FieldDescriptor) *openapi
 		schema = openapi3.NewIntegerSchema().WithMin(0).WithMax(math.MaxUint32)
 
 	case descriptorpb.FieldDescriptorProto_TYPE_UINT64:
-		if g.intNative {
+		if g.protoJSONNumbers {
+			schema = newProtoJSONInt64Schema(true)
+		} else if g.intNative {
 			// we don't set the max here beacause it is too large to be represented without scientific notation
 			// in YAML format
 			schema = openapi3.NewIntegerSchema().WithMin(0).WithFormat("uint64")
@@ -754,15 +2191,22 @@ func (g *openapiGenerator) fieldType(field *protomodel.FieldDescriptor) *openapi
 	case descriptorpb.FieldDescriptorProto_TYPE_STRING:
 		schema = openapi3.NewStringSchema()
 
-	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP:
 		msg := field.FieldType.(*protomodel.MessageDescriptor)
-		if soloSchema, ok := g.customSchemasByMessageName[g.absoluteName(msg)]; ok {
+		if numberSchema := g.protoJSONWrapperSchema(msg); numberSchema != nil {
+			schema = g.generateSoloMessageSchema(msg, numberSchema)
+		} else if anyTypes := field.AnyTypes(); len(anyTypes) > 0 && g.absoluteName(msg) == "google.protobuf.Any" {
+			schema = g.newAnySchema(msg, anyTypes)
+		} else if soloSchema, ok := g.customSchema(msg); ok {
 			// Allow for defining special Solo types
 			schema = g.generateSoloMessageSchema(msg, &soloSchema)
 		} else if msg.GetOptions().GetMapEntry() {
 			isMap = true
 			sr := g.fieldTypeRef(msg.Fields[1])
-			if g.useRef && sr.Ref != "" {
+			if sr.Ref == "" {
+				sr.Ref = g.mapValueRef(msg.Fields[1])
+			}
+			if sr.Ref != "" {
 				schema = openapi3.NewObjectSchema()
 				// in `$ref`, the value of the schema is not in the output.
 				sr.Value = nil
@@ -770,6 +2214,13 @@ func (g *openapiGenerator) fieldType(field *protomodel.FieldDescriptor) *openapi
 			} else {
 				schema = openapi3.NewObjectSchema().WithAdditionalProperties(sr.Value)
 			}
+			if g.validateMapKeys && !g.disableKubeMarkers {
+				applyMapKeyValidation(msg.Fields[0], schema)
+			}
+		} else if ref := g.typeRef(msg); ref != "" {
+			schema = g.newRefSchema(ref, &openapi3.Types{openapi3.TypeObject})
+		} else if g.isRecursive(msg) {
+			schema = g.generateRecursiveMessageSchema(msg)
 		} else {
 			schema = g.generateMessageSchema(msg)
 		}
@@ -779,7 +2230,15 @@ func (g *openapiGenerator) fieldType(field *protomodel.FieldDescriptor) *openapi
 
 	case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
 		enum := field.FieldType.(*protomodel.EnumDescriptor)
+		if g.absoluteName(enum) == "google.protobuf.NullValue" {
+			// protojson encodes the only value of NullValue as `null`
+			schema = &openapi3.Schema{Nullable: true, Enum: []interface{}{nil}}
+			break
+		}
 		schema = g.generateEnumSchema(enum)
+		if ref := g.typeRef(enum); ref != "" {
+			schema = g.newRefSchema(ref, schema.Type)
+		}
 	}
 
 	if field.IsRepeated() && !isMap {
@@ -788,6 +2247,10 @@ func (g *openapiGenerator) fieldType(field *protomodel.FieldDescriptor) *openapi
 
 	if schema != nil {
 		schema.Description = g.generateDescription(field)
+		// fields of a deprecated message or enum inherit its marking, unless they are deprecated themselves
+		if field.FieldType != nil && !isDeprecated(field) {
+			g.applyDeprecation(field.FieldType, schema)
+		}
 	}
 
 	return schema
@@ -797,18 +2260,138 @@ func (g *openapiGenerator) fieldType(field *protomodel.FieldDescriptor) *openapi
 func (g *openapiGenerator) fieldTypeRef(field *protomodel.FieldDescriptor) *openapi3.SchemaRef {
 	s := g.fieldType(field)
 	var ref string
-	if *field.Type == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
-		msg := field.FieldType.(*protomodel.MessageDescriptor)
-		// only generate `$ref` for top level messages.
-		if _, ok := g.messages[g.relativeName(field.FieldType)]; ok && msg.Parent == nil {
-			ref = fmt.Sprintf("#/components/schemas/%v", g.absoluteName(field.FieldType))
+	if !field.IsRepeated() {
+		switch t := field.FieldType.(type) {
+		case *protomodel.MessageDescriptor:
+			ref = g.typeRef(t)
+		case *protomodel.EnumDescriptor:
+			ref = g.typeRef(t)
 		}
 	}
 	return openapi3.NewSchemaRef(ref, s)
 }
 
-func (g *openapiGenerator) fieldName(field *protomodel.FieldDescriptor) string {
-	return field.GetJsonName()
+// typeRef returns the `$ref` to the component of a message or enum when it is referenced instead of
+// inlined, and schedules the generation of the component.
+func (g *openapiGenerator) typeRef(desc protomodel.CoreDesc) string {
+	var inDocument, nested bool
+	var enum bool
+	switch d := desc.(type) {
+	case *protomodel.MessageDescriptor:
+		// custom schemas and maps are always inlined
+		if _, ok := g.customSchema(d); ok || d.GetOptions().GetMapEntry() {
+			return ""
+		}
+		_, inDocument = g.messages[g.relativeName(d)]
+		nested = d.Parent != nil
+	case *protomodel.EnumDescriptor:
+		_, inDocument = g.enums[g.relativeName(d)]
+		nested = len(d.QualifiedName()) > 1
+		enum = true
+	default:
+		return ""
+	}
+
+	// nested types only have a component of their own when they are referenced from their document.
+	if !inDocument && !nested {
+		if ref := g.externalTypeRef(desc); ref != "" {
+			return ref
+		}
+	}
+
+	if !g.useRef {
+		return ""
+	}
+	// only generate `$ref` for enums when nested types are referenced too.
+	if enum && (g.refMode == RefMapValues || g.refMode == RefInlineNested) {
+		return ""
+	}
+
+	switch g.refMode {
+	case RefAll:
+	case RefNested:
+		if !inDocument {
+			return ""
+		}
+	case RefMapValues:
+		// the values of maps are referenced by mapValueRef
+		return ""
+	default:
+		if !inDocument || nested {
+			return ""
+		}
+	}
+	return g.componentRef(desc)
+}
+
+// mapValueRef returns the `$ref` to the component of the values of a map with `ref_mode=map_values`,
+// which are referenced when they are top-level messages of the current output document.
+func (g *openapiGenerator) mapValueRef(field *protomodel.FieldDescriptor) string {
+	msg, ok := field.FieldType.(*protomodel.MessageDescriptor)
+	if !ok || !g.useRef || g.refMode != RefMapValues {
+		return ""
+	}
+	if _, ok := g.customSchema(msg); ok || msg.GetOptions().GetMapEntry() {
+		return ""
+	}
+	if _, inDocument := g.messages[g.relativeName(msg)]; !inDocument || msg.Parent != nil {
+		return ""
+	}
+	return g.componentRef(msg)
+}
+
+// externalTypeRef returns the `$ref` to the component of a message or enum defined outside of the
+// current output document, when such types are not inlined.
+func (g *openapiGenerator) externalTypeRef(desc protomodel.CoreDesc) string {
+	var document string
+	switch g.externalRef {
+	case ExternalRefFile:
+		// the type needs to be generated in another document of the output
+		if g.singleFile || !g.filesToGen[desc.FileDesc()] {
+			return ""
+		}
+		if g.perFile {
+			document = perFileOutputName(desc.FileDesc()) + g.fileExtension()
+		} else {
+			document = desc.PackageDesc().Name + g.fileExtension()
+		}
+	case ExternalRefLocation:
+		document = desc.FileDesc().Matter.HomeLocation
+	}
+	if document == "" {
+		return ""
+	}
+	return document + "#/components/schemas/" + g.absoluteName(desc)
+}
+
+// componentRef returns the `$ref` to the component of a message or enum, and schedules the
+// generation of the component.
+func (g *openapiGenerator) componentRef(desc protomodel.CoreDesc) string {
+	name := g.absoluteName(desc)
+	g.pendingComponents[name] = desc
+	return "#/components/schemas/" + name
+}
+
+// newRefSchema wraps a `$ref` so the description and markers of a field can be set alongside it.
+func (g *openapiGenerator) newRefSchema(ref string, types *openapi3.Types) *openapi3.Schema {
+	return &openapi3.Schema{
+		Type:  types,
+		AllOf: openapi3.SchemaRefs{openapi3.NewSchemaRef(ref, nil)},
+	}
+}
+
+// fieldNames returns the names of the properties of a field, which are the same spelling of the
+// field unless both the JSON and proto names are accepted.
+func (g *openapiGenerator) fieldNames(field *protomodel.FieldDescriptor) []string {
+	switch g.fieldNaming {
+	case FieldNamingProto:
+		return []string{field.GetName()}
+	case FieldNamingBoth:
+		if field.GetJsonName() != field.GetName() {
+			return []string{field.GetJsonName(), field.GetName()}
+		}
+	}
+	return []string{field.GetJsonName()}
 }
 
 func (g *openapiGenerator) relativeName(desc protomodel.CoreDesc) string {
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/solo-io/protoc-gen-openapi/pkg/markers"
+	"github.com/solo-io/protoc-gen-openapi/pkg/protomodel"
+)
+
+func TestParseComments(t *testing.T) {
+	m := protomodel.NewModel(newDiamondRequest(1, 1), false)
+	g := newOpenAPIGenerator(m, GeneratorOptions{
+		SingleFile:  true,
+		Description: &DescriptionConfiguration{IncludeDescriptionInSchema: true, MultilineDescription: true},
+	})
+	msg := m.AllDescByName[".bench.Layer0Msg0"].(*protomodel.MessageDescriptor)
+
+	parsed := g.parseComments(msg)
+	if parsed.description != "A message of the diamond." {
+		t.Errorf("unexpected description %q", parsed.description)
+	}
+	if len(parsed.markers) != 1 {
+		t.Fatalf("expected 1 marker, got %d", len(parsed.markers))
+	}
+	if v, ok := parsed.markers[0].Value.(markers.MaxProperties); !ok || v != 100 {
+		t.Errorf("unexpected marker value %#v", parsed.markers[0].Value)
+	}
+	if g.parseComments(msg) != parsed {
+		t.Errorf("expected the parsed comments to be cached")
+	}
+
+	name := g.parseComments(msg.Fields[0])
+	if v, ok := name.markers[0].Value.(markers.MaxLength); !ok || v != 64 {
+		t.Errorf("unexpected marker value %#v", name.markers[0].Value)
+	}
+	if name.markers.IsRequired() || name.markers.SchemaType() != "" {
+		t.Errorf("unexpected Required or Type marker in %#v", name.markers)
+	}
+}
@@ -1,8 +1,10 @@
 package markers
 
 import (
+	"fmt"
 	"log"
 	"math"
+	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
@@ -15,10 +17,11 @@ func (m Maximum) Value() float64 {
 }
 
 func (m Maximum) ApplyToSchema(o *openapi3.Schema) {
-	if !hasNumericType(o) {
+	n := numericSchema(o)
+	if n == nil {
 		log.Panicf("Maximum constraint applied to non-numeric type %s", o.Type)
 	}
-	o.WithMax(m.Value())
+	n.WithMax(m.Value())
 }
 
 // Minimum specifies the minimum numeric value that this field can have. Negative numbers are supported.
@@ -29,30 +32,33 @@ func (m Minimum) Value() float64 {
 }
 
 func (m Minimum) ApplyToSchema(o *openapi3.Schema) {
-	if !hasNumericType(o) {
+	n := numericSchema(o)
+	if n == nil {
 		log.Panicf("must apply Minimum to a numeric type, got %s", o.Type)
 	}
-	o.WithMin(m.Value())
+	n.WithMin(m.Value())
 }
 
 // ExclusiveMinimum indicates that the minimum is "up to" but not including that value.
 type ExclusiveMinimum bool
 
 func (m ExclusiveMinimum) ApplyToSchema(o *openapi3.Schema) {
-	if !hasNumericType(o) {
+	n := numericSchema(o)
+	if n == nil {
 		log.Panicf("must apply ExclusiveMinimum to a numeric type, got %s", o.Type)
 	}
-	o.WithExclusiveMin(bool(m))
+	n.WithExclusiveMin(bool(m))
 }
 
 // ExclusiveMaximum indicates that the maximum is "up to" but not including that value.
 type ExclusiveMaximum bool
 
 func (m ExclusiveMaximum) ApplyToSchema(o *openapi3.Schema) {
-	if !hasNumericType(o) {
+	n := numericSchema(o)
+	if n == nil {
 		log.Panicf("must apply ExclusiveMaximum to a numeric type, got %s", o.Type)
 	}
-	o.WithExclusiveMax(bool(m))
+	n.WithExclusiveMax(bool(m))
 }
 
 // MultipleOf specifies that this field must have a numeric value that's a multiple of this one.
@@ -63,14 +69,15 @@ func (m MultipleOf) Value() float64 {
 }
 
 func (m MultipleOf) ApplyToSchema(o *openapi3.Schema) {
-	if !hasNumericType(o) {
+	n := numericSchema(o)
+	if n == nil {
 		log.Panicf("must apply MultipleOf to a numeric type, got %s", o.Type)
 	}
-	if o.Type.Is(openapi3.TypeInteger) && !isIntegral(m.Value()) {
+	if n.Type.Is(openapi3.TypeInteger) && !isIntegral(m.Value()) {
 		log.Panicf("cannot apply non-integral MultipleOf validation (%v) to integer value", m.Value())
 	}
 	val := m.Value()
-	o.MultipleOf = &val
+	n.MultipleOf = &val
 }
 
 // MaxProperties restricts the number of keys in an object
@@ -262,7 +269,42 @@ func (x XValidation) ApplyToSchema(o *openapi3.Schema) {
 	} else if o.Extensions[validationsHeader] == nil {
 		o.Extensions[validationsHeader] = []XValidation{}
 	}
-	o.Extensions[validationsHeader] = append(o.Extensions[validationsHeader].([]XValidation), x)
+	// copy the rules since the slice may be shared with other copies of the schema
+	rules := o.Extensions[validationsHeader].([]XValidation)
+	o.Extensions[validationsHeader] = append(rules[:len(rules):len(rules)], x)
+}
+
+// KeysPattern specifies that the keys of this map must match the given regular expression.
+type KeysPattern string
+
+func (m KeysPattern) ApplyToSchema(o *openapi3.Schema) {
+	mustBeMap(o, "KeysPattern")
+	XValidation{
+		Rule:    fmt.Sprintf("self.all(k, k.matches(%s))", celRawString(string(m))),
+		Message: fmt.Sprintf("map keys must match the pattern %s", string(m)),
+	}.ApplyToSchema(o)
+}
+
+// KeysMaxLength specifies the maximum length of the keys of this map.
+type KeysMaxLength int
+
+func (m KeysMaxLength) ApplyToSchema(o *openapi3.Schema) {
+	mustBeMap(o, "KeysMaxLength")
+	XValidation{
+		Rule:    fmt.Sprintf("self.all(k, size(k) <= %d)", int(m)),
+		Message: fmt.Sprintf("map keys must be at most %d characters long", int(m)),
+	}.ApplyToSchema(o)
+}
+
+// KeysMinLength specifies the minimum length of the keys of this map.
+type KeysMinLength int
+
+func (m KeysMinLength) ApplyToSchema(o *openapi3.Schema) {
+	mustBeMap(o, "KeysMinLength")
+	XValidation{
+		Rule:    fmt.Sprintf("self.all(k, size(k) >= %d)", int(m)),
+		Message: fmt.Sprintf("map keys must be at least %d characters long", int(m)),
+	}.ApplyToSchema(o)
 }
 
 // Nullable marks this field as allowing the "null" value.
@@ -330,10 +372,46 @@ func (m Required) ApplyToSchema(o *openapi3.Schema) {
 	// nothing to do, it is applied on the top level message containing the required field
 }
 
+func mustBeMap(o *openapi3.Schema, marker string) {
+	if !o.Type.Is(openapi3.TypeObject) || o.AdditionalProperties.Schema == nil {
+		log.Panicf("must apply %s to a map, got %s", marker, o.Type)
+	}
+}
+
+// celRawString quotes s as a CEL string literal, raw when possible so that the backslashes of regular
+// expressions are kept as is.
+func celRawString(s string) string {
+	switch {
+	case !strings.Contains(s, "'"):
+		return "r'" + s + "'"
+	case !strings.Contains(s, `"`):
+		return `r"` + s + `"`
+	default:
+		// s contains both quotes, so it is escaped into a regular literal
+		return "'" + celStringEscaper.Replace(s) + "'"
+	}
+}
+
+var celStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
 func hasNumericType(o *openapi3.Schema) bool {
 	return o.Type.Is(openapi3.TypeInteger) || o.Type.Is(openapi3.TypeNumber)
 }
 
+// numericSchema returns the schema numeric constraints apply to, which is either the schema itself or
+// its numeric alternative when the number may also be encoded as a string.
+func numericSchema(o *openapi3.Schema) *openapi3.Schema {
+	if hasNumericType(o) {
+		return o
+	}
+	for _, alt := range o.OneOf {
+		if alt.Value != nil && hasNumericType(alt.Value) {
+			return alt.Value
+		}
+	}
+	return nil
+}
+
 func isIntegral(value float64) bool {
 	return value == math.Trunc(value) && !math.IsNaN(value) && !math.IsInf(value, 0)
 }
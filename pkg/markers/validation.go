@@ -3,7 +3,6 @@ package markers
 import (
 	"fmt"
 	"log"
-	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"sigs.k8s.io/controller-tools/pkg/markers"
@@ -38,6 +37,9 @@ var (
 	_ SchemaMarker = XEmbeddedResource{}
 	_ SchemaMarker = XIntOrString{}
 	_ SchemaMarker = XValidation{}
+	_ SchemaMarker = KeysPattern("")
+	_ SchemaMarker = KeysMaxLength(0)
+	_ SchemaMarker = KeysMinLength(0)
 )
 
 // ValidationMarkers lists all available markers that affect CRD schema generation,
@@ -95,6 +97,13 @@ var FieldOnlyMarkers = []*definitionWithHelp{
 	must(markers.MakeDefinition("kubebuilder:validation:Schemaless", markers.DescribesField, Schemaless{})),
 }
 
+// MapKeyMarkers list the validation markers of the keys of map fields, which are all string in JSON.
+var MapKeyMarkers = []*definitionWithHelp{
+	must(markers.MakeDefinition("kubebuilder:validation:keys:Pattern", markers.DescribesField, KeysPattern(""))),
+	must(markers.MakeDefinition("kubebuilder:validation:keys:MaxLength", markers.DescribesField, KeysMaxLength(0))),
+	must(markers.MakeDefinition("kubebuilder:validation:keys:MinLength", markers.DescribesField, KeysMinLength(0))),
+}
+
 // ValidationIshMarkers are field-and-type markers that don't fall under the
 // :validation: prefix, and/or don't have a name that directly matches their
 // type.
@@ -122,72 +131,87 @@ func init() {
 	}
 
 	AllDefinitions = append(AllDefinitions, FieldOnlyMarkers...)
+	AllDefinitions = append(AllDefinitions, MapKeyMarkers...)
 	AllDefinitions = append(AllDefinitions, ValidationIshMarkers...)
 }
 
-func (r *Registry) MustApplyRulesToSchema(
+// Marker is a kubebuilder marker parsed from a comment.
+type Marker struct {
+	// Rule is the marker as written in the comment
+	Rule string
+
+	// Value is the parsed value of the marker
+	Value interface{}
+}
+
+// Markers are the markers parsed from the comments of a descriptor.
+type Markers []Marker
+
+// MustParse parses the given rules and panics if any of them is invalid.
+func (r *Registry) MustParse(
 	rules []string,
-	o *openapi3.Schema,
 	target markers.TargetType,
-) {
-	err := r.ApplyRulesToSchema(rules, o, target)
+) Markers {
+	m, err := r.Parse(rules, target)
 	if err != nil {
-		log.Panicf("error applying rules to schema: %s", err)
+		log.Panicf("error parsing rules: %s", err)
 	}
+	return m
 }
 
-func (r *Registry) ApplyRulesToSchema(
+// Parse looks up and parses each rule once, so that the resulting markers can be shared
+// by everything that inspects them.
+func (r *Registry) Parse(
 	rules []string,
-	o *openapi3.Schema,
 	target markers.TargetType,
-) error {
+) (Markers, error) {
+	var m Markers
 	for _, rule := range rules {
 		defn := r.mRegistry.Lookup(rule, target)
 		if defn == nil {
-			return fmt.Errorf("no definition found for rule: %s", rule)
+			return nil, fmt.Errorf("no definition found for rule: %s", rule)
 		}
 		val, err := defn.Parse(rule)
 		if err != nil {
-			return fmt.Errorf("error parsing rule: %s", err)
+			return nil, fmt.Errorf("error parsing rule: %s", err)
 		}
-		if s, ok := val.(SchemaMarker); ok {
+		m = append(m, Marker{Rule: rule, Value: val})
+	}
+	return m, nil
+}
+
+func (m Markers) MustApplyToSchema(o *openapi3.Schema) {
+	err := m.ApplyToSchema(o)
+	if err != nil {
+		log.Panicf("error applying rules to schema: %s", err)
+	}
+}
+
+func (m Markers) ApplyToSchema(o *openapi3.Schema) error {
+	for _, marker := range m {
+		if s, ok := marker.Value.(SchemaMarker); ok {
 			s.ApplyToSchema(o)
 		} else {
-			return fmt.Errorf("expected SchemaMarker, got %T", val)
+			return fmt.Errorf("expected SchemaMarker, got %T", marker.Value)
 		}
 	}
 	return nil
 }
 
-func (r *Registry) GetSchemaType(
-	rules []string,
-	target markers.TargetType,
-) Type {
-	for _, rule := range rules {
-		defn := r.mRegistry.Lookup(rule, target)
-		if defn == nil {
-			log.Panicf("no definition found for rule: %s", rule)
-		}
-		val, err := defn.Parse(rule)
-		if err != nil {
-			log.Panicf("error parsing rule: %s", err)
-		}
-		if s, ok := val.(Type); ok {
-			return s
+// SchemaType returns the value of the Type marker, if any.
+func (m Markers) SchemaType() Type {
+	for _, marker := range m {
+		if t, ok := marker.Value.(Type); ok {
+			return t
 		}
 	}
 	return ""
 }
 
-func (r *Registry) IsRequired(
-	rules []string,
-) bool {
-	for _, rule := range rules {
-		defn := r.mRegistry.Lookup(rule, markers.DescribesField)
-		if defn == nil {
-			log.Panicf("no definition found for rule: %s", rule)
-		}
-		if strings.HasPrefix(rule, "+kubebuilder:validation:Required") {
+// IsRequired returns true if the Required marker is present.
+func (m Markers) IsRequired() bool {
+	for _, marker := range m {
+		if _, ok := marker.Value.(Required); ok {
 			return true
 		}
 	}
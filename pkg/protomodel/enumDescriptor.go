@@ -15,13 +15,16 @@
 package protomodel
 
 import (
+	"strings"
+
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 type EnumDescriptor struct {
 	baseDesc
 	*descriptorpb.EnumDescriptorProto
-	Values []*EnumValueDescriptor // The values of this enum
+	Values   []*EnumValueDescriptor // The values of this enum
+	features *descriptorpb.FeatureSet
 }
 
 type EnumValueDescriptor struct {
@@ -39,9 +42,15 @@ func newEnumDescriptor(desc *descriptorpb.EnumDescriptorProto, parent *MessageDe
 		qualifiedName = append(qualifiedName, desc.GetName())
 	}
 
+	parentFeatures := file.Features()
+	if parent != nil {
+		parentFeatures = parent.Features()
+	}
+
 	e := &EnumDescriptor{
 		EnumDescriptorProto: desc,
 		baseDesc:            newBaseDesc(file, path, qualifiedName),
+		features:            mergeFeatures(parentFeatures, desc.GetOptions().GetFeatures()),
 	}
 
 	e.Values = make([]*EnumValueDescriptor, 0, len(desc.Value))
@@ -59,3 +68,31 @@ func newEnumDescriptor(desc *descriptorpb.EnumDescriptorProto, parent *MessageDe
 
 	return e
 }
+
+// Features returns the resolved features of the enum.
+func (e *EnumDescriptor) Features() *descriptorpb.FeatureSet {
+	return e.features
+}
+
+// IsClosed returns true if the enum only accepts its declared values, as decided by its
+// `enum_type` feature.
+func (e *EnumDescriptor) IsClosed() bool {
+	return e.features.GetEnumType() == descriptorpb.FeatureSet_CLOSED
+}
+
+// IsUnspecified returns true if the value is the zero value sentinel which is never set by users, either
+// because it is named `*_UNSPECIFIED` or because it is annotated with `$unspecified`.
+func (ev *EnumValueDescriptor) IsUnspecified() bool {
+	if ev.GetNumber() != 0 {
+		return false
+	}
+	if ev.GetName() == "UNSPECIFIED" || strings.HasSuffix(ev.GetName(), "_UNSPECIFIED") {
+		return true
+	}
+	return strings.Contains(ev.Location().GetLeadingComments(), "$unspecified")
+}
+
+// IsDeprecated returns true if the value is marked with the `deprecated` option.
+func (ev *EnumValueDescriptor) IsDeprecated() bool {
+	return ev.GetOptions().GetDeprecated()
+}
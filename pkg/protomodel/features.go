@@ -0,0 +1,64 @@
+package protomodel
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The range of editions supported by the model.
+const (
+	MinimumEdition = descriptorpb.Edition_EDITION_PROTO2
+	MaximumEdition = descriptorpb.Edition_EDITION_2023
+)
+
+// fileEdition returns the edition of a file, mapping the legacy proto2 and proto3 syntaxes
+// to their equivalent editions.
+func fileEdition(desc *descriptorpb.FileDescriptorProto) descriptorpb.Edition {
+	switch desc.GetSyntax() {
+	case "editions":
+		return desc.GetEdition()
+	case "proto3":
+		return descriptorpb.Edition_EDITION_PROTO3
+	default:
+		return descriptorpb.Edition_EDITION_PROTO2
+	}
+}
+
+// editionDefaults returns the default values of the features that affect the generated schemas
+// for the given edition.
+func editionDefaults(edition descriptorpb.Edition) *descriptorpb.FeatureSet {
+	switch {
+	case edition >= descriptorpb.Edition_EDITION_2023:
+		return &descriptorpb.FeatureSet{
+			FieldPresence:         descriptorpb.FeatureSet_EXPLICIT.Enum(),
+			EnumType:              descriptorpb.FeatureSet_OPEN.Enum(),
+			RepeatedFieldEncoding: descriptorpb.FeatureSet_PACKED.Enum(),
+			MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+		}
+	case edition == descriptorpb.Edition_EDITION_PROTO3:
+		return &descriptorpb.FeatureSet{
+			FieldPresence:         descriptorpb.FeatureSet_IMPLICIT.Enum(),
+			EnumType:              descriptorpb.FeatureSet_OPEN.Enum(),
+			RepeatedFieldEncoding: descriptorpb.FeatureSet_PACKED.Enum(),
+			MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+		}
+	default:
+		return &descriptorpb.FeatureSet{
+			FieldPresence:         descriptorpb.FeatureSet_EXPLICIT.Enum(),
+			EnumType:              descriptorpb.FeatureSet_CLOSED.Enum(),
+			RepeatedFieldEncoding: descriptorpb.FeatureSet_EXPANDED.Enum(),
+			MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+		}
+	}
+}
+
+// mergeFeatures resolves the features of a descriptor by overriding the features inherited from
+// its parent with the ones explicitly set in its options.
+func mergeFeatures(parent *descriptorpb.FeatureSet, override *descriptorpb.FeatureSet) *descriptorpb.FeatureSet {
+	if override == nil {
+		return parent
+	}
+	resolved := proto.Clone(parent).(*descriptorpb.FeatureSet)
+	proto.Merge(resolved, override)
+	return resolved
+}
@@ -0,0 +1,43 @@
+package protomodel
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// FieldBehavior is a value of the `google.api.field_behavior` field option.
+type FieldBehavior int32
+
+// The values of the `google.api.FieldBehavior` enum.
+const (
+	FieldBehaviorUnspecified     FieldBehavior = 0
+	FieldBehaviorOptional        FieldBehavior = 1
+	FieldBehaviorRequired        FieldBehavior = 2
+	FieldBehaviorOutputOnly      FieldBehavior = 3
+	FieldBehaviorInputOnly       FieldBehavior = 4
+	FieldBehaviorImmutable       FieldBehavior = 5
+	FieldBehaviorUnorderedList   FieldBehavior = 6
+	FieldBehaviorNonEmptyDefault FieldBehavior = 7
+	FieldBehaviorIdentifier      FieldBehavior = 8
+)
+
+// The field number of the `google.api.field_behavior` extension of `google.protobuf.FieldOptions`.
+const fieldBehaviorExtension protowire.Number = 1052
+
+// FieldBehaviors returns the values of the `google.api.field_behavior` option of the field.
+func (f *FieldDescriptor) FieldBehaviors() []FieldBehavior {
+	var behaviors []FieldBehavior
+	for _, v := range varintValues(unknownFieldsOf(f.GetOptions(), fieldBehaviorExtension)) {
+		behaviors = append(behaviors, FieldBehavior(v))
+	}
+	return behaviors
+}
+
+// HasFieldBehavior returns true if the field is annotated with the given `google.api.field_behavior`.
+func (f *FieldDescriptor) HasFieldBehavior(behavior FieldBehavior) bool {
+	for _, b := range f.FieldBehaviors() {
+		if b == behavior {
+			return true
+		}
+	}
+	return false
+}
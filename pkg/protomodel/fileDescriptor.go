@@ -29,6 +29,8 @@ type FileDescriptor struct {
 	Dependencies []*FileDescriptor                                    // Files imported by this file
 	locations    map[pathVector]*descriptorpb.SourceCodeInfo_Location // Provenance
 	Matter       FrontMatter                                          // Title, overview, homeLocation, front_matter
+	Edition      descriptorpb.Edition                                 // Edition, or the equivalent of the proto2/proto3 syntax
+	features     *descriptorpb.FeatureSet                             // Resolved features of the file
 }
 
 func newFileDescriptor(desc *descriptorpb.FileDescriptorProto, parent *PackageDescriptor) *FileDescriptor {
@@ -36,7 +38,9 @@ func newFileDescriptor(desc *descriptorpb.FileDescriptorProto, parent *PackageDe
 		FileDescriptorProto: desc,
 		locations:           make(map[pathVector]*descriptorpb.SourceCodeInfo_Location, len(desc.GetSourceCodeInfo().GetLocation())),
 		Parent:              parent,
+		Edition:             fileEdition(desc),
 	}
+	f.features = mergeFeatures(editionDefaults(f.Edition), desc.GetOptions().GetFeatures())
 
 	// put all the locations in a map for quick lookup
 	for _, loc := range desc.GetSourceCodeInfo().GetLocation() {
@@ -77,6 +81,11 @@ func newFileDescriptor(desc *descriptorpb.FileDescriptorProto, parent *PackageDe
 	return f
 }
 
+// Features returns the resolved features of the file.
+func (f *FileDescriptor) Features() *descriptorpb.FeatureSet {
+	return f.features
+}
+
 func (f *FileDescriptor) find(path pathVector) *descriptorpb.SourceCodeInfo_Location {
 	loc := f.locations[path]
 	return loc
@@ -15,6 +15,13 @@
 package protomodel
 
 import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -25,12 +32,23 @@ type MessageDescriptor struct {
 	Messages []*MessageDescriptor // Inner messages, if any
 	Enums    []*EnumDescriptor    // Inner enums, if any
 	Fields   []*FieldDescriptor   // Fields, if any
+	Oneofs   []*OneofDescriptor   // Oneofs, if any
+	features *descriptorpb.FeatureSet
 }
 
 type FieldDescriptor struct {
 	baseDesc
 	*descriptorpb.FieldDescriptorProto
-	FieldType CoreDesc // Type of data held by this field
+	FieldType CoreDesc         // Type of data held by this field
+	Oneof     *OneofDescriptor // The oneof containing this field, if any
+	features  *descriptorpb.FeatureSet
+}
+
+type OneofDescriptor struct {
+	baseDesc
+	*descriptorpb.OneofDescriptorProto
+	Parent *MessageDescriptor // The message declaring this oneof
+	Fields []*FieldDescriptor // The members of this oneof
 }
 
 func newMessageDescriptor(desc *descriptorpb.DescriptorProto, parent *MessageDescriptor, file *FileDescriptor, path pathVector) *MessageDescriptor {
@@ -43,10 +61,16 @@ func newMessageDescriptor(desc *descriptorpb.DescriptorProto, parent *MessageDes
 		qualifiedName = append(qualifiedName, desc.GetName())
 	}
 
+	parentFeatures := file.Features()
+	if parent != nil {
+		parentFeatures = parent.Features()
+	}
+
 	m := &MessageDescriptor{
 		DescriptorProto: desc,
 		Parent:          parent,
 		baseDesc:        newBaseDesc(file, path, qualifiedName),
+		features:        mergeFeatures(parentFeatures, desc.GetOptions().GetFeatures()),
 	}
 
 	for i, f := range desc.Field {
@@ -57,11 +81,30 @@ func newMessageDescriptor(desc *descriptorpb.DescriptorProto, parent *MessageDes
 		fd := &FieldDescriptor{
 			FieldDescriptorProto: f,
 			baseDesc:             newBaseDesc(file, path.append(messageFieldPath, i), nameCopy),
+			features:             mergeFeatures(m.features, f.GetOptions().GetFeatures()),
 		}
 
 		m.Fields = append(m.Fields, fd)
 	}
 
+	for i, o := range desc.OneofDecl {
+		nameCopy := make([]string, len(qualifiedName), len(qualifiedName)+1)
+		copy(nameCopy, qualifiedName)
+		nameCopy = append(nameCopy, o.GetName())
+
+		m.Oneofs = append(m.Oneofs, &OneofDescriptor{
+			OneofDescriptorProto: o,
+			Parent:               m,
+			baseDesc:             newBaseDesc(file, path.append(messageOneofPath, i), nameCopy),
+		})
+	}
+	for _, fd := range m.Fields {
+		if fd.OneofIndex != nil && int(fd.GetOneofIndex()) < len(m.Oneofs) {
+			fd.Oneof = m.Oneofs[fd.GetOneofIndex()]
+			fd.Oneof.Fields = append(fd.Oneof.Fields, fd)
+		}
+	}
+
 	for i, msg := range desc.NestedType {
 		m.Messages = append(m.Messages, newMessageDescriptor(msg, m, file, path.append(messageMessagePath, i)))
 	}
@@ -73,6 +116,179 @@ func newMessageDescriptor(desc *descriptorpb.DescriptorProto, parent *MessageDes
 	return m
 }
 
+// Features returns the resolved features of the message.
+func (m *MessageDescriptor) Features() *descriptorpb.FeatureSet {
+	return m.features
+}
+
+// Features returns the resolved features of the field.
+func (f *FieldDescriptor) Features() *descriptorpb.FeatureSet {
+	return f.features
+}
+
+// IsMessage returns true if the field holds a message, whether length-prefixed or delimited.
+func (f *FieldDescriptor) IsMessage() bool {
+	return f.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE ||
+		f.GetType() == descriptorpb.FieldDescriptorProto_TYPE_GROUP
+}
+
+// HasPresence returns true if the field tracks whether it was set, as decided by its
+// `field_presence` feature. Message fields and members of a oneof always have presence.
+func (f *FieldDescriptor) HasPresence() bool {
+	if f.IsRepeated() {
+		return false
+	}
+	if f.IsMessage() || f.OneofIndex != nil {
+		return true
+	}
+	return f.features.GetFieldPresence() != descriptorpb.FeatureSet_IMPLICIT
+}
+
+// IsRequired returns true if the field must be set, either through the proto2 `required` label
+// or the `LEGACY_REQUIRED` value of its `field_presence` feature.
+func (f *FieldDescriptor) IsRequired() bool {
+	return f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED ||
+		f.features.GetFieldPresence() == descriptorpb.FeatureSet_LEGACY_REQUIRED
+}
+
+// JSONDefaultValue returns the `default_value` of the field, typed as its JSON representation.
+// Non-finite floating point defaults have no JSON number representation and are not returned.
+func (f *FieldDescriptor) JSONDefaultValue() (interface{}, bool) {
+	if f.FieldDescriptorProto.DefaultValue == nil {
+		return nil, false
+	}
+	v := f.GetDefaultValue()
+
+	switch f.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, descriptorpb.FieldDescriptorProto_TYPE_FLOAT:
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsInf(d, 0) || math.IsNaN(d) {
+			return nil, false
+		}
+		return d, true
+
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32, descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return i, true
+
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT64, descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
+		u, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		return u, true
+
+	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+
+	case descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		// bytes defaults are C-escaped, while their JSON representation is base64
+		b, err := unescapeBytes(v)
+		if err != nil {
+			return nil, false
+		}
+		return base64.StdEncoding.EncodeToString(b), true
+
+	default:
+		// strings and enum value names are used as is
+		return v, true
+	}
+}
+
+// unescapeBytes reverses the C-style escaping protoc applies to the default value of bytes fields.
+func unescapeBytes(s string) ([]byte, error) {
+	var b []byte
+	for len(s) > 0 {
+		if s[0] != '\\' {
+			b = append(b, s[0])
+			s = s[1:]
+			continue
+		}
+		if len(s) < 2 {
+			return nil, fmt.Errorf("invalid escape sequence at end of %q", s)
+		}
+		switch c := s[1]; {
+		case c >= '0' && c <= '7':
+			n := 1
+			for n < 3 && 1+n < len(s) && s[1+n] >= '0' && s[1+n] <= '7' {
+				n++
+			}
+			v, err := strconv.ParseUint(s[1:1+n], 8, 8)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, byte(v))
+			s = s[1+n:]
+		case c == 'x' || c == 'X':
+			n := 0
+			for n < 2 && 2+n < len(s) && isHexDigit(s[2+n]) {
+				n++
+			}
+			v, err := strconv.ParseUint(s[2:2+n], 16, 8)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, byte(v))
+			s = s[2+n:]
+		default:
+			r, ok := simpleEscapes[c]
+			if !ok {
+				return nil, fmt.Errorf("invalid escape sequence \\%c", c)
+			}
+			b = append(b, r)
+			s = s[2:]
+		}
+	}
+	return b, nil
+}
+
+var simpleEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	'\\': '\\', '\'': '\'', '"': '"', '?': '?',
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
 func (f *FieldDescriptor) IsRepeated() bool {
 	return f.Label != nil && *f.Label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
 }
+
+// IsProto3Optional returns true for proto3 `optional` fields, which have explicit presence
+// and are tracked by the compiler through a synthetic oneof.
+func (f *FieldDescriptor) IsProto3Optional() bool {
+	return f.GetProto3Optional()
+}
+
+const anyTypesTag = "$any_types:"
+
+// AnyTypes returns the fully qualified names of the messages a `google.protobuf.Any` field may hold,
+// as listed by the `$any_types:` annotation of the field, separated by commas or spaces.
+func (f *FieldDescriptor) AnyTypes() []string {
+	for _, line := range strings.Split(f.Location().GetLeadingComments(), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, anyTypesTag) {
+			return strings.FieldsFunc(line[len(anyTypesTag):], func(r rune) bool {
+				return r == ',' || unicode.IsSpace(r)
+			})
+		}
+	}
+	return nil
+}
+
+// IsSynthetic returns true for the oneofs generated by the compiler to track the presence of proto3
+// `optional` fields, which aren't declared in the proto file.
+func (o *OneofDescriptor) IsSynthetic() bool {
+	return len(o.Fields) == 1 && o.Fields[0].IsProto3Optional()
+}
@@ -26,6 +26,9 @@ type Model struct {
 	AllFilesByName map[string]*FileDescriptor
 	AllDescByName  map[string]CoreDesc
 	Packages       []*PackageDescriptor
+
+	// The `google.api.resource` descriptors of all the input protos, by resource type
+	AllResourcesByType map[string]*ResourceDescriptor
 }
 
 func NewModel(request *pluginpb.CodeGeneratorRequest, perFile bool) *Model {
@@ -63,6 +66,8 @@ func NewModel(request *pluginpb.CodeGeneratorRequest, perFile bool) *Model {
 		resolveDependencies(f, m.AllFilesByName)
 	}
 
+	m.AllResourcesByType = createResourceMap(allFiles, m.AllDescByName)
+
 	return m
 }
 
@@ -88,6 +93,25 @@ func packageName(f *descriptorpb.FileDescriptorProto) string {
 	return name
 }
 
+// createResourceMap builds a map from resource types to the resources declared by messages, or by
+// the files when no message is annotated with their type.
+func createResourceMap(files []*FileDescriptor, descMap map[string]CoreDesc) map[string]*ResourceDescriptor {
+	resources := make(map[string]*ResourceDescriptor)
+	for _, f := range files {
+		for _, r := range f.ResourceDefinitions() {
+			resources[r.Type] = r
+		}
+	}
+	for _, desc := range descMap {
+		if msg, ok := desc.(*MessageDescriptor); ok {
+			if r := msg.Resource(); r != nil {
+				resources[r.Type] = r
+			}
+		}
+	}
+	return resources
+}
+
 // createDescMap builds a map from qualified names to descriptors.
 // The key names for the map come from the input data, which puts a period at the beginning.
 func createDescMap(files []*FileDescriptor) map[string]CoreDesc {
@@ -124,6 +148,10 @@ func recordMessages(messages []*MessageDescriptor, descMap map[string]CoreDesc,
 		for _, f := range msg.Fields {
 			descMap[dottedPkg+DottedName(f)] = f
 		}
+
+		for _, o := range msg.Oneofs {
+			descMap[dottedPkg+DottedName(o)] = o
+		}
 	}
 }
 
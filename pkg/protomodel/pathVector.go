@@ -37,6 +37,7 @@ const (
 	messageFieldPath   = 2 // field
 	messageMessagePath = 3 // nested_type
 	messageEnumPath    = 4 // enum_type
+	messageOneofPath   = 8 // oneof_decl
 
 	// tag numbers in EnumDescriptorProto
 	enumValuePath = 2 // value
@@ -0,0 +1,78 @@
+package protomodel
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The field numbers of the `google.api.resource`, `google.api.resource_definition` and
+// `google.api.resource_reference` extensions.
+const (
+	resourceExtension           protowire.Number = 1053 // of google.protobuf.MessageOptions
+	resourceDefinitionExtension protowire.Number = 1053 // of google.protobuf.FileOptions
+	resourceReferenceExtension  protowire.Number = 1055 // of google.protobuf.FieldOptions
+)
+
+// ResourceDescriptor holds the fields of a `google.api.ResourceDescriptor` the schemas are derived from.
+type ResourceDescriptor struct {
+	// The resource type, such as `example.googleapis.com/Cluster`
+	Type string
+	// The patterns of the resource names, such as `projects/{project}/clusters/{cluster}`
+	Patterns []string
+	// The field of the message holding the resource name, `name` when unset
+	NameField string
+}
+
+// ResourceReference holds the fields of a `google.api.ResourceReference`.
+type ResourceReference struct {
+	// The type of the referenced resource
+	Type string
+	// The type of the resources the referenced resource is a parent of, when Type is unset
+	ChildType string
+}
+
+func newResourceDescriptor(b []byte) *ResourceDescriptor {
+	r := &ResourceDescriptor{
+		Type:      lastString(decodeFields(b, 1)),
+		Patterns:  stringValues(decodeFields(b, 2)),
+		NameField: lastString(decodeFields(b, 3)),
+	}
+	if r.NameField == "" {
+		r.NameField = "name"
+	}
+	return r
+}
+
+// Resource returns the `google.api.resource` option of the message, or nil if it isn't a resource.
+func (m *MessageDescriptor) Resource() *ResourceDescriptor {
+	fields := unknownFieldsOf(m.GetOptions(), resourceExtension)
+	if len(fields) == 0 || fields[len(fields)-1].typ != protowire.BytesType {
+		return nil
+	}
+	return newResourceDescriptor(fields[len(fields)-1].value)
+}
+
+// ResourceDefinitions returns the resources declared by the `google.api.resource_definition` option
+// of the file, which describe resources without a message of their own.
+func (f *FileDescriptor) ResourceDefinitions() []*ResourceDescriptor {
+	var resources []*ResourceDescriptor
+	for _, field := range unknownFieldsOf(f.GetOptions(), resourceDefinitionExtension) {
+		if field.typ == protowire.BytesType {
+			resources = append(resources, newResourceDescriptor(field.value))
+		}
+	}
+	return resources
+}
+
+// ResourceReference returns the `google.api.resource_reference` option of the field, or nil if it
+// doesn't reference a resource.
+func (f *FieldDescriptor) ResourceReference() *ResourceReference {
+	fields := unknownFieldsOf(f.GetOptions(), resourceReferenceExtension)
+	if len(fields) == 0 || fields[len(fields)-1].typ != protowire.BytesType {
+		return nil
+	}
+	b := fields[len(fields)-1].value
+	return &ResourceReference{
+		Type:      lastString(decodeFields(b, 1)),
+		ChildType: lastString(decodeFields(b, 2)),
+	}
+}
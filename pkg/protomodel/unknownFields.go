@@ -0,0 +1,95 @@
+package protomodel
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// The extensions of the descriptor options read by the model aren't registered with the plugin, so
+// they are decoded from the unknown fields of the options.
+
+// unknownField is the raw value of a field, without its tag. Length-prefixed values are stripped of
+// their length.
+type unknownField struct {
+	typ   protowire.Type
+	value []byte
+}
+
+// unknownFieldsOf returns the values of the unknown fields of the message with the given number.
+func unknownFieldsOf(m proto.Message, num protowire.Number) []unknownField {
+	if m == nil || !m.ProtoReflect().IsValid() {
+		return nil
+	}
+	return decodeFields(m.ProtoReflect().GetUnknown(), num)
+}
+
+// decodeFields returns the values of the fields of the encoded message with the given number.
+// Decoding stops at the first malformed field.
+func decodeFields(b []byte, num protowire.Number) []unknownField {
+	var fields []unknownField
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return fields
+		}
+		b = b[l:]
+
+		l = protowire.ConsumeFieldValue(n, typ, b)
+		if l < 0 {
+			return fields
+		}
+		if n == num {
+			value := b[:l]
+			if typ == protowire.BytesType {
+				value, _ = protowire.ConsumeBytes(value)
+			}
+			fields = append(fields, unknownField{typ: typ, value: value})
+		}
+		b = b[l:]
+	}
+	return fields
+}
+
+// varintValues returns the values of a varint field, which may be packed when repeated.
+func varintValues(fields []unknownField) []uint64 {
+	var values []uint64
+	for _, f := range fields {
+		switch f.typ {
+		case protowire.VarintType:
+			if v, l := protowire.ConsumeVarint(f.value); l >= 0 {
+				values = append(values, v)
+			}
+		case protowire.BytesType:
+			for b := f.value; len(b) > 0; {
+				v, l := protowire.ConsumeVarint(b)
+				if l < 0 {
+					break
+				}
+				values = append(values, v)
+				b = b[l:]
+			}
+		}
+	}
+	return values
+}
+
+// stringValues returns the values of a string field.
+func stringValues(fields []unknownField) []string {
+	var values []string
+	for _, f := range fields {
+		if f.typ == protowire.BytesType {
+			values = append(values, string(f.value))
+		}
+	}
+	return values
+}
+
+// lastString returns the value of a singular string field, which is the last one when it is repeated
+// in the encoding.
+func lastString(fields []unknownField) string {
+	values := stringValues(fields)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}